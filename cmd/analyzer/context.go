@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/cache"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/events"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/knowledge"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/logmine"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/prompt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultContextTokens is the context budget used when llm.contextTokens is unset
+	defaultContextTokens = 6000
+
+	defaultCacheTTL       = time.Hour
+	defaultCacheConfigMap = "kube-ai-sre-agent-cache"
+
+	defaultKnowledgeConfigMap = "kube-ai-sre-agent-knowledge"
+	defaultKnowledgeTopK      = 3
+	defaultMinSimilarity      = 0.3
+
+	// defaultTailLines is how many log lines are fetched per container when
+	// logs.tailLines is unset; compressed logs fit far more lines in the prompt
+	defaultTailLines           = 100
+	defaultCompressedTailLines = 1000
+	// defaultStackTraceLines is how many log lines are searched for stack traces
+	defaultStackTraceLines = 5000
+
+	// noLogs stands in for the logs of containers that never started
+	noLogs = "(No logs available)"
+
+	defaultRunbookTopK          = 3
+	defaultRunbookMinSimilarity = 0.2
+)
+
+// collectSections gathers the incident context as prioritized sections. For
+// crashes the previous container's logs usually hold the failure, so they
+// outrank the logs of the freshly restarted container.
+func collectSections(ctx context.Context, clientset *kubernetes.Clientset, logsCfg *config.LogsConfig, eventType, namespace, podName, containerName string) []*prompt.Section {
+	podInfo, err := getPodInfo(ctx, clientset, namespace, podName)
+	if err != nil {
+		klog.Errorf("Failed to get pod info: %v", err)
+		podInfo = fmt.Sprintf("Failed to get pod info: %v", err)
+	}
+
+	podEvents, err := getPodEvents(ctx, clientset, namespace, podName)
+	if err != nil {
+		klog.Warningf("Failed to get pod events: %v", err)
+	}
+
+	tailLines := logsCfg.TailLines
+	if tailLines <= 0 {
+		tailLines = defaultTailLines
+		if logsCfg.Compress {
+			tailLines = defaultCompressedTailLines
+		}
+	}
+
+	// Compression reads the kubelet's timestamps for the first and last
+	// occurrence of each template, also for applications that log none
+	timestamps := logsCfg.Compress
+
+	// Stack traces are searched in a longer window than the tail sent to the
+	// model, so a trace whose header scrolled out of the tail is still found
+	fetchLines := tailLines
+	if logsCfg.StackTraces {
+		stackTraceLines := logsCfg.StackTraceLines
+		if stackTraceLines <= 0 {
+			stackTraceLines = defaultStackTraceLines
+		}
+		if stackTraceLines > fetchLines {
+			fetchLines = stackTraceLines
+		}
+	}
+
+	rawLogs, err := getPodLogsWithOptions(ctx, clientset, namespace, podName, containerName, false, fetchLines, timestamps)
+	if err != nil {
+		klog.V(2).Infof("Current logs unavailable: %v", err)
+		rawLogs = ""
+	}
+
+	rawPrevious, err := getPodLogsWithOptions(ctx, clientset, namespace, podName, containerName, true, fetchLines, timestamps)
+	if err != nil {
+		klog.V(2).Infof("Previous container logs unavailable: %v", err)
+		rawPrevious = ""
+	}
+
+	// Extract stack traces from the raw logs before compression or truncation
+	// can split them
+	var stackTraces string
+	if logsCfg.StackTraces {
+		stackTraces = extractStackTraces(rawPrevious, rawLogs, timestamps)
+	}
+	logs, previousLogs := lastLines(rawLogs, tailLines), lastLines(rawPrevious, tailLines)
+
+	if logs == "" {
+		logs = noLogs
+	} else if logsCfg.Compress {
+		logs = compressLogs("current", logs)
+	}
+	if logsCfg.Compress && previousLogs != "" {
+		previousLogs = compressLogs("previous", previousLogs)
+	}
+
+	logsPriority, previousPriority := 20, 10
+	switch events.EventType(eventType) {
+	case events.CrashLoopBackOff, events.OOMKilled:
+		logsPriority, previousPriority = 10, 20
+	}
+
+	return []*prompt.Section{
+		{Name: "pod", Title: "Pod Information", Content: podInfo, Priority: 40, MinTokens: 200, Truncate: prompt.TruncateTail, Untrusted: true},
+		{Name: "events", Title: "Pod Events", Content: podEvents, Priority: 30, MinTokens: 150, Truncate: prompt.TruncateHead, Untrusted: true},
+		{Name: "logs", Title: "Pod Logs", Content: logs, Priority: logsPriority, MinTokens: 300, Truncate: prompt.TruncateHead, Untrusted: true},
+		{Name: "previousLogs", Title: "Previous Container Logs", Content: previousLogs, Priority: previousPriority, MinTokens: 300, Truncate: prompt.TruncateHead, Untrusted: true},
+		{Name: "stackTraces", Title: "Stack Traces", Content: stackTraces, Priority: 50, Truncate: prompt.TruncateTail, Pinned: true, Untrusted: true},
+	}
+}
+
+// extractStackTraces renders the stack traces found in the raw logs of the
+// previous and current container
+func extractStackTraces(previous, current string, timestamps bool) string {
+	raw := previous + "\n" + current
+	if timestamps {
+		raw = logmine.StripTimestamps(raw)
+	}
+	traces := logmine.ExtractStackTraces(raw)
+	if len(traces) == 0 {
+		return ""
+	}
+	klog.Infof("Extracted %d stack traces from the logs", len(traces))
+	return logmine.RenderStackTraces(traces)
+}
+
+// lastLines returns the last n lines of text
+func lastLines(text string, n int64) string {
+	if n <= 0 {
+		return text
+	}
+	body := strings.TrimSuffix(text, "\n")
+	start := len(body)
+	for ; n > 0; n-- {
+		start = strings.LastIndexByte(body[:start], '\n')
+		if start < 0 {
+			return text
+		}
+	}
+	return text[start+1:]
+}
+
+// probeSection describes the failing probe of a HealthCheckFailure: its type,
+// the kubelet's failure message and the probe configuration from the pod spec
+func probeSection(probeType, message, probeConfig string, restarted bool) *prompt.Section {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Probe: %s\n", probeType)
+	fmt.Fprintf(&b, "Failure: %s\n", message)
+	if probeConfig != "" {
+		fmt.Fprintf(&b, "Configuration: %s\n", probeConfig)
+	}
+	if restarted {
+		b.WriteString("The kubelet restarted the container because this probe kept failing.\n")
+	}
+	return &prompt.Section{Name: "probe", Title: "Failing Probe", Content: b.String(), Priority: 45, MinTokens: 100, Truncate: prompt.TruncateTail, Untrusted: true}
+}
+
+// compressLogs replaces repeated log lines by templates with counts
+func compressLogs(which, logs string) string {
+	compressed, lines, templates := logmine.Compress(logs)
+	if templates == lines {
+		// Too little repetition; send the lines as the application wrote them
+		return logmine.StripTimestamps(logs)
+	}
+	klog.Infof("Compressed %d %s log lines into %d templates (%d -> %d bytes)",
+		lines, which, templates, len(logs), len(compressed))
+	return compressed
+}
+
+// collectObjectSections gathers the context of an incident about an object
+// other than a pod, which has no status or logs the analyzer knows how to read
+func collectObjectSections(ctx context.Context, clientset *kubernetes.Clientset, kind, namespace, name string) []*prompt.Section {
+	objectEvents, err := getObjectEvents(ctx, clientset, namespace, kind, name)
+	if err != nil {
+		klog.Warningf("Failed to get %s events: %v", kind, err)
+	}
+	return []*prompt.Section{
+		{Name: "events", Title: kind + " Events", Content: objectEvents, Priority: 30, MinTokens: 150, Truncate: prompt.TruncateHead, Untrusted: true},
+	}
+}
+
+// getPodEvents lists the events of a pod, oldest first
+func getPodEvents(ctx context.Context, clientset *kubernetes.Clientset, namespace, podName string) (string, error) {
+	return getObjectEvents(ctx, clientset, namespace, "Pod", podName)
+}
+
+// getObjectEvents lists the events of an object, oldest first
+func getObjectEvents(ctx context.Context, clientset *kubernetes.Clientset, namespace, kind, name string) (string, error) {
+	selector := fields.SelectorFromSet(fields.Set{
+		"involvedObject.kind": kind,
+		"involvedObject.name": name,
+	})
+
+	list, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{FieldSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+
+	items := list.Items
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].LastTimestamp.Before(&items[j].LastTimestamp)
+	})
+
+	var b strings.Builder
+	for _, e := range items {
+		fmt.Fprintf(&b, "%s %s %s (x%d): %s\n",
+			e.LastTimestamp.Format("15:04:05"), e.Type, e.Reason, e.Count, strings.TrimSpace(e.Message))
+	}
+	return b.String(), nil
+}
+
+// incidentFingerprint identifies incidents that would get the same analysis:
+// same event type, image and exit code, and matching normalized log tail. When
+// there are no logs, e.g. for pods that were never scheduled or objects other
+// than pods, the event message stands in for them.
+func incidentFingerprint(ctx context.Context, clientset *kubernetes.Clientset, eventType, objectKind, namespace, podName, containerName, message string, sections []*prompt.Section) string {
+	in := cache.Incident{EventType: eventType, Namespace: namespace, PodName: podName}
+
+	in.Logs = crashLogs(sections)
+	if in.Logs == "" || in.Logs == noLogs {
+		in.Logs = message
+	}
+	if objectKind != "" {
+		return cache.Fingerprint(in)
+	}
+
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		klog.Warningf("Failed to get pod for fingerprint: %v", err)
+	} else {
+		statuses := append(append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...),
+			pod.Status.ContainerStatuses...), pod.Status.EphemeralContainerStatuses...)
+		for _, cs := range statuses {
+			if containerName != "" && cs.Name != containerName {
+				continue
+			}
+			in.Image = cs.Image
+			if t := cs.LastTerminationState.Terminated; t != nil {
+				in.ExitCode = t.ExitCode
+			}
+			if t := cs.State.Terminated; t != nil {
+				in.ExitCode = t.ExitCode
+			}
+			break
+		}
+	}
+	return cache.Fingerprint(in)
+}
+
+// crashLogs returns the logs most likely to hold the failure: the previous
+// instance's logs, falling back to the current ones
+func crashLogs(sections []*prompt.Section) string {
+	for _, name := range []string{"previousLogs", "logs"} {
+		for _, s := range sections {
+			if s.Name == name && s.Content != "" {
+				return s.Content
+			}
+		}
+	}
+	return ""
+}
+
+// newAnalysisCache opens the configured cache backend
+func newAnalysisCache(cfg *config.CacheConfig, clientset *kubernetes.Clientset, namespace string) (*cache.Cache, error) {
+	ttl := time.Duration(cfg.TTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+
+	switch cfg.Backend {
+	case "file":
+		if cfg.Path == "" || cfg.PersistentVolumeClaim == "" {
+			return nil, fmt.Errorf("file cache backend requires a path and a persistentVolumeClaim")
+		}
+		return cache.New(cache.NewFileStore(cfg.Path), ttl), nil
+	case "", "configmap":
+		if namespace == "" {
+			return nil, fmt.Errorf("configmap cache backend requires the NAMESPACE environment variable")
+		}
+		name := cfg.ConfigMap
+		if name == "" {
+			name = defaultCacheConfigMap
+		}
+		return cache.New(cache.NewConfigMapStore(clientset, namespace, name), ttl), nil
+	default:
+		return nil, fmt.Errorf("unsupported cache backend: %s", cfg.Backend)
+	}
+}
+
+// newKnowledgeBase opens the configured knowledge base backend
+func newKnowledgeBase(cfg *config.KnowledgeConfig, clientset *kubernetes.Clientset, namespace string) (*knowledge.Base, error) {
+	switch cfg.Backend {
+	case "file":
+		if cfg.Path == "" || cfg.PersistentVolumeClaim == "" {
+			return nil, fmt.Errorf("file knowledge base backend requires a path and a persistentVolumeClaim")
+		}
+		return knowledge.New(knowledge.NewFileStore(cfg.Path), cfg.MaxIncidents), nil
+	case "", "configmap":
+		if namespace == "" {
+			return nil, fmt.Errorf("configmap knowledge base backend requires the NAMESPACE environment variable")
+		}
+		name := cfg.ConfigMap
+		if name == "" {
+			name = defaultKnowledgeConfigMap
+		}
+		return knowledge.New(knowledge.NewConfigMapStore(clientset, namespace, name), cfg.MaxIncidents), nil
+	default:
+		return nil, fmt.Errorf("unsupported knowledge base backend: %s", cfg.Backend)
+	}
+}
+
+// searchKnowledge returns the past incidents most similar to this one; a
+// failing knowledge base only costs the extra context
+func searchKnowledge(ctx context.Context, kb *knowledge.Base, cfg *config.KnowledgeConfig, fingerprint, eventType, summary string) []knowledge.Match {
+	topK := cfg.TopK
+	if topK <= 0 {
+		topK = defaultKnowledgeTopK
+	}
+	minSimilarity := cfg.MinSimilarity
+	if minSimilarity <= 0 {
+		minSimilarity = defaultMinSimilarity
+	}
+
+	matches, err := kb.Search(ctx, fingerprint, eventType, summary, topK, minSimilarity)
+	if err != nil {
+		klog.Warningf("Knowledge base unavailable: %v", err)
+		return nil
+	}
+	for _, m := range matches {
+		klog.Infof("Similar past incident %s in %s/%s (similarity %.2f)", m.Record.EventType, m.Record.Namespace, m.Record.PodName, m.Score)
+	}
+	return matches
+}
+
+// searchRunbooks loads the runbooks from the configured directory and
+// ConfigMaps and returns the sections most relevant to the incident
+func searchRunbooks(ctx context.Context, cfg *config.RunbooksConfig, clientset *kubernetes.Clientset, namespace, incidentText string) []knowledge.RunbookMatch {
+	docs := map[string]string{}
+	if cfg.Dir != "" {
+		loaded, err := knowledge.LoadRunbookDir(cfg.Dir)
+		if err != nil {
+			klog.Warningf("Failed to load runbooks: %v", err)
+		}
+		for source, text := range loaded {
+			docs[source] = text
+		}
+	}
+	if cfg.ConfigMapSelector != "" {
+		loaded, err := knowledge.LoadRunbookConfigMaps(ctx, clientset, namespace, cfg.ConfigMapSelector)
+		if err != nil {
+			klog.Warningf("Failed to load runbooks: %v", err)
+		}
+		for source, text := range loaded {
+			docs["configmap/"+source] = text
+		}
+	}
+
+	topK := cfg.TopK
+	if topK <= 0 {
+		topK = defaultRunbookTopK
+	}
+	minSimilarity := cfg.MinSimilarity
+	if minSimilarity <= 0 {
+		minSimilarity = defaultRunbookMinSimilarity
+	}
+
+	runbooks := knowledge.NewRunbooks(docs)
+	matches := runbooks.Search(incidentText, topK, minSimilarity)
+	klog.Infof("Selected %d of %d runbook sections from %d runbooks", len(matches), runbooks.Len(), len(docs))
+	return matches
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/logmine"
+)
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		n    int64
+		want string
+	}{
+		{"fewer lines than n", "a\nb\n", 5, "a\nb\n"},
+		{"exactly n lines", "a\nb\n", 2, "a\nb\n"},
+		{"keeps the tail", "a\nb\nc\n", 2, "b\nc\n"},
+		{"no trailing newline", "a\nb\nc", 1, "c"},
+		{"empty", "", 3, ""},
+		{"unlimited", "a\nb\n", 0, "a\nb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastLines(tt.text, tt.n); got != tt.want {
+				t.Errorf("lastLines(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractStackTracesAboveTheTail(t *testing.T) {
+	// A deep JVM stack pushes the exception line out of the last 100 lines
+	var b strings.Builder
+	b.WriteString("2024-05-01T10:00:00.000000000Z Exception in thread \"main\" java.lang.IllegalStateException: pool exhausted\n")
+	for i := 0; i < 150; i++ {
+		b.WriteString("2024-05-01T10:00:00.000000000Z \tat com.example.Pool.acquire(Pool.java:42)\n")
+	}
+	raw := b.String()
+
+	tail := lastLines(raw, defaultTailLines)
+	if traces := logmine.ExtractStackTraces(logmine.StripTimestamps(tail)); len(traces) != 0 {
+		t.Fatalf("the tail alone yields %d traces, want the header cut off", len(traces))
+	}
+
+	got := extractStackTraces("", raw, true)
+	if !strings.Contains(got, "java.lang.IllegalStateException: pool exhausted") {
+		t.Errorf("extractStackTraces() = %q, want the exception above the tail", got)
+	}
+}
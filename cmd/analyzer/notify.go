@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/guard"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/knowledge"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+	"k8s.io/klog/v2"
+)
+
+// maxListedInjections bounds the injection findings listed in a notification
+const maxListedInjections = 5
+
+// incidentReport is everything included in the incident notification
+type incidentReport struct {
+	EventType string
+	// Kind is set for incidents about objects other than pods
+	Kind      string
+	Namespace string
+	PodName   string
+	Container string
+	// ContainerKind is init or ephemeral for containers other than the regular ones
+	ContainerKind string
+
+	// Analysis is nil when the LLM step failed or was skipped; AnalysisErr or
+	// SkipReason explains why
+	Analysis    *llm.Analysis
+	AnalysisErr error
+	SkipReason  string
+	// ReusedFrom is when the cached analysis was made, zero for a fresh one
+	ReusedFrom time.Time
+	// Similar lists the past incidents added to the prompt
+	Similar []knowledge.Match
+	// Runbooks lists the runbook sections added to the prompt
+	Runbooks []knowledge.RunbookMatch
+	// Commands counts the suggested commands flagged by the command check
+	Commands guard.CommandReport
+	// Injections are context lines that looked like instructions to the model
+	Injections []guard.Finding
+	// OutputCheck lists why the analysis failed the output check; the analysis
+	// is nil when it was rejected
+	OutputCheck []string
+
+	// Redactions summarizes values masked before the context left the cluster
+	Redactions      int
+	RedactionDetail string
+}
+
+// formatNotification renders the incident and its analysis for Slack
+func formatNotification(report *incidentReport) string {
+	var b strings.Builder
+	if report.Kind != "" {
+		fmt.Fprintf(&b, "%s: %s %s/%s\n\n", report.EventType, report.Kind, report.Namespace, report.PodName)
+	} else if report.ContainerKind == "init" || report.ContainerKind == "ephemeral" {
+		fmt.Fprintf(&b, "%s: %s/%s (%s container %s)\n\n", report.EventType, report.Namespace, report.PodName, report.ContainerKind, report.Container)
+	} else {
+		fmt.Fprintf(&b, "%s: %s/%s\n\n", report.EventType, report.Namespace, report.PodName)
+	}
+
+	if analysis := report.Analysis; analysis == nil && report.SkipReason != "" {
+		fmt.Fprintf(&b, "AI analysis skipped: %s\n", report.SkipReason)
+	} else if analysis == nil && len(report.OutputCheck) > 0 {
+		fmt.Fprintf(&b, "AI analysis withheld, it failed the safety check: %s\n", strings.Join(report.OutputCheck, "; "))
+	} else if analysis == nil {
+		fmt.Fprintf(&b, "AI analysis unavailable (%s): %v\n", llm.ClassOf(report.AnalysisErr), report.AnalysisErr)
+	} else {
+		b.WriteString(analysis.String())
+		b.WriteString("\n\n")
+
+		if !report.ReusedFrom.IsZero() {
+			fmt.Fprintf(&b, "Reused analysis from %s (identical incident)\n", report.ReusedFrom.UTC().Format("2006-01-02 15:04 MST"))
+		}
+		fmt.Fprintf(&b, "Analyzed by %s", analysis.Provider)
+		if analysis.Model != "" {
+			fmt.Fprintf(&b, " (%s)", analysis.Model)
+		}
+		if len(analysis.FallbackFrom) > 0 {
+			fmt.Fprintf(&b, " after fallback from %s", strings.Join(analysis.FallbackFrom, ", "))
+		}
+		b.WriteString("\n")
+		if len(analysis.Samples) > 0 {
+			total := len(analysis.Samples) + 1
+			fmt.Fprintf(&b, "Consensus: %.0f of %d samples agree (%.0f%%)",
+				analysis.Agreement*float64(total), total, analysis.Agreement*100)
+			if len(analysis.Alternatives) > 0 {
+				fmt.Fprintf(&b, "; alternatives: %s", strings.Join(analysis.Alternatives, "; "))
+			}
+			b.WriteString("\n")
+		}
+		if triage := analysis.Triage; triage != nil {
+			fmt.Fprintf(&b, "Escalated from %s (%s)", triage.Model, analysis.EscalationReason)
+			if triage.Structured {
+				fmt.Fprintf(&b, "; triage said: %s (severity %s, confidence %.0f%%)", triage.RootCause, triage.Severity, triage.Confidence*100)
+			}
+			b.WriteString("\n")
+		}
+		if usage := analysis.TotalUsage(); usage.Total() > 0 {
+			fmt.Fprintf(&b, "Tokens: %d in / %d out", usage.InputTokens, usage.OutputTokens)
+			if usage.CostUSD > 0 {
+				fmt.Fprintf(&b, " (~$%.4f)", usage.CostUSD)
+			}
+			b.WriteString("\n")
+		}
+		if c := report.Commands; c.Destructive+c.Missing > 0 {
+			fmt.Fprintf(&b, "Command check: %d destructive", c.Destructive)
+			if c.Stripped > 0 {
+				fmt.Fprintf(&b, " (%d removed)", c.Stripped)
+			}
+			fmt.Fprintf(&b, ", %d mutating, %d naming missing resources; review before running\n", c.Mutating, c.Missing)
+		}
+		if len(report.OutputCheck) > 0 {
+			fmt.Fprintf(&b, "Safety check warnings: %s\n", strings.Join(report.OutputCheck, "; "))
+		}
+		if len(analysis.ToolCalls) > 0 {
+			fmt.Fprintf(&b, "Investigated with: %s\n", strings.Join(analysis.ToolCalls, ", "))
+		}
+		if len(report.Similar) > 0 {
+			similar := make([]string, 0, len(report.Similar))
+			for _, m := range report.Similar {
+				similar = append(similar, fmt.Sprintf("%s/%s on %s (%.0f%%)", m.Record.Namespace, m.Record.PodName, m.Record.LastSeen.UTC().Format("2006-01-02"), m.Score*100))
+			}
+			fmt.Fprintf(&b, "Similar past incidents: %s\n", strings.Join(similar, ", "))
+		}
+	}
+
+	if len(report.Injections) > 0 {
+		findings := make([]string, 0, len(report.Injections))
+		for i, f := range report.Injections {
+			if i == maxListedInjections {
+				findings = append(findings, fmt.Sprintf("and %d more", len(report.Injections)-i))
+				break
+			}
+			findings = append(findings, f.String())
+		}
+		fmt.Fprintf(&b, "Possible prompt injection in the incident context: %s\n", strings.Join(findings, ", "))
+	}
+
+	// Runbooks help the on-call even when the analysis failed or was skipped
+	if len(report.Runbooks) > 0 {
+		b.WriteString("Related runbooks:\n")
+		for _, m := range report.Runbooks {
+			fmt.Fprintf(&b, "  - %s (%s)\n", m.Section.Name(), m.Section.Source)
+		}
+	}
+
+	if report.Redactions > 0 {
+		fmt.Fprintf(&b, "Redacted %d sensitive values before analysis (%s)\n", report.Redactions, report.RedactionDetail)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func sendSlackNotification(webhook, eventType, namespace, podName, message string) error {
+	// TODO: Implement actual Slack API call
+	klog.Infof("Sending to Slack: %s incident for %s/%s", eventType, namespace, podName)
+	klog.V(2).Infof("Slack message:\n%s", message)
+	return nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/events"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// maxToolOutput caps a tool result in characters so one call cannot exhaust the token budget
+const maxToolOutput = 8000
+
+// clusterTools exposes read-only lookups to the model in agent mode. Every
+// lookup is confined to the incident's namespace, plus the nodes.
+type clusterTools struct {
+	clientset *kubernetes.Clientset
+	namespace string
+	podName   string
+
+	// transcript collects the tool output the model saw, for the output check
+	transcript strings.Builder
+}
+
+// toolArgs is the union of the arguments accepted by the cluster tools
+type toolArgs struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+	Previous  bool   `json:"previous"`
+	Service   string `json:"service"`
+	Node      string `json:"node"`
+}
+
+func stringParam(description string) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description}
+}
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Tools describes the available tools to the model
+func (t *clusterTools) Tools() []llm.Tool {
+	podParam := stringParam("Pod name; defaults to the incident pod")
+	return []llm.Tool{
+		{
+			Name:        "get_pod_events",
+			Description: "List the Kubernetes events of a pod in the incident namespace, oldest first.",
+			Parameters:  objectSchema(map[string]interface{}{"pod": podParam}),
+		},
+		{
+			Name:        "describe_owner",
+			Description: "Describe the workload that owns a pod (Deployment, StatefulSet, DaemonSet or Job): replicas, rollout status, conditions and container specs.",
+			Parameters:  objectSchema(map[string]interface{}{"pod": podParam}),
+		},
+		{
+			Name:        "get_container_logs",
+			Description: "Get the last 100 log lines of a container of a pod, e.g. a sidecar or another container than the failing one.",
+			Parameters: objectSchema(map[string]interface{}{
+				"pod":       podParam,
+				"container": stringParam("Container name"),
+				"previous":  map[string]interface{}{"type": "boolean", "description": "Return logs of the previous, terminated instance"},
+			}, "container"),
+		},
+		{
+			Name:        "get_service_endpoints",
+			Description: "List the ready and not-ready endpoints of a Service in the incident namespace.",
+			Parameters:  objectSchema(map[string]interface{}{"service": stringParam("Service name")}, "service"),
+		},
+		{
+			Name:        "get_node_conditions",
+			Description: "Get the conditions, capacity, taints and schedulability of a node.",
+			Parameters:  objectSchema(map[string]interface{}{"node": stringParam("Node name; defaults to the node of the incident pod")}),
+		},
+	}
+}
+
+// Execute runs a tool call requested by the model
+func (t *clusterTools) Execute(ctx context.Context, call llm.ToolCall) (string, error) {
+	var args toolArgs
+	if len(call.Arguments) > 0 {
+		if err := json.Unmarshal(call.Arguments, &args); err != nil {
+			return "", fmt.Errorf("invalid arguments for %s: %w", call.Name, err)
+		}
+	}
+	if args.Pod == "" {
+		args.Pod = t.podName
+	}
+
+	var out string
+	var err error
+	switch call.Name {
+	case "get_pod_events":
+		out, err = getPodEvents(ctx, t.clientset, t.namespace, args.Pod)
+		if err == nil && out == "" {
+			out = "(no events)"
+		}
+	case "describe_owner":
+		out, err = t.describeOwner(ctx, args.Pod)
+	case "get_container_logs":
+		if args.Container == "" {
+			return "", fmt.Errorf("container is required")
+		}
+		out, err = getPodLogsWithOptions(ctx, t.clientset, t.namespace, args.Pod, args.Container, args.Previous, defaultTailLines, false)
+		if err == nil && out == "" {
+			out = "(no logs)"
+		}
+	case "get_service_endpoints":
+		if args.Service == "" {
+			return "", fmt.Errorf("service is required")
+		}
+		out, err = t.serviceEndpoints(ctx, args.Service)
+	case "get_node_conditions":
+		out, err = t.nodeConditions(ctx, args.Node)
+	default:
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if runes := []rune(out); len(runes) > maxToolOutput {
+		out = string(runes[len(runes)-maxToolOutput:]) + "\n[... output truncated ...]"
+	}
+	return out, nil
+}
+
+// describeOwner follows the pod's controller reference, resolving ReplicaSets to their Deployment
+func (t *clusterTools) describeOwner(ctx context.Context, podName string) (string, error) {
+	pod, err := t.clientset.CoreV1().Pods(t.namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return fmt.Sprintf("Pod %s has no controlling owner", podName), nil
+	}
+
+	apps := t.clientset.AppsV1()
+	switch owner.Kind {
+	case "ReplicaSet":
+		rs, err := apps.ReplicaSets(t.namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		rsOwner := metav1.GetControllerOf(rs)
+		if rsOwner == nil || rsOwner.Kind != "Deployment" {
+			return fmt.Sprintf("ReplicaSet %s: %d/%d ready\n%s", rs.Name, rs.Status.ReadyReplicas, rs.Status.Replicas, describePodSpec(&rs.Spec.Template.Spec)), nil
+		}
+		d, err := apps.Deployments(t.namespace).Get(ctx, rsOwner.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return describeDeployment(d), nil
+	case "StatefulSet":
+		s, err := apps.StatefulSets(t.namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("StatefulSet %s: replicas=%d ready=%d current=%d updated=%d\nRevision: current=%s update=%s\n%s",
+			s.Name, replicas(s.Spec.Replicas), s.Status.ReadyReplicas, s.Status.CurrentReplicas, s.Status.UpdatedReplicas,
+			s.Status.CurrentRevision, s.Status.UpdateRevision, describePodSpec(&s.Spec.Template.Spec)), nil
+	case "DaemonSet":
+		ds, err := apps.DaemonSets(t.namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("DaemonSet %s: desired=%d ready=%d available=%d updated=%d misscheduled=%d\n%s",
+			ds.Name, ds.Status.DesiredNumberScheduled, ds.Status.NumberReady, ds.Status.NumberAvailable,
+			ds.Status.UpdatedNumberScheduled, ds.Status.NumberMisscheduled, describePodSpec(&ds.Spec.Template.Spec)), nil
+	case "Job":
+		job, err := t.clientset.BatchV1().Jobs(t.namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Job %s: active=%d succeeded=%d failed=%d\n%s",
+			job.Name, job.Status.Active, job.Status.Succeeded, job.Status.Failed, describePodSpec(&job.Spec.Template.Spec)), nil
+	}
+	return fmt.Sprintf("Pod %s is owned by %s %s, which cannot be described", podName, owner.Kind, owner.Name), nil
+}
+
+func describeDeployment(d *appsv1.Deployment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Deployment %s: replicas=%d ready=%d available=%d updated=%d unavailable=%d\n",
+		d.Name, replicas(d.Spec.Replicas), d.Status.ReadyReplicas, d.Status.AvailableReplicas,
+		d.Status.UpdatedReplicas, d.Status.UnavailableReplicas)
+	fmt.Fprintf(&b, "Strategy: %s, revision %s\n", d.Spec.Strategy.Type, d.Annotations["deployment.kubernetes.io/revision"])
+	b.WriteString("Conditions:\n")
+	for _, c := range d.Status.Conditions {
+		fmt.Fprintf(&b, "  - %s=%s (%s): %s\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+	b.WriteString(describePodSpec(&d.Spec.Template.Spec))
+	return b.String()
+}
+
+func describePodSpec(spec *corev1.PodSpec) string {
+	var b strings.Builder
+	b.WriteString("Containers:\n")
+	for _, c := range spec.Containers {
+		fmt.Fprintf(&b, "  - %s: image=%s\n", c.Name, c.Image)
+		if len(c.Resources.Requests) > 0 || len(c.Resources.Limits) > 0 {
+			fmt.Fprintf(&b, "    requests: cpu=%s memory=%s; limits: cpu=%s memory=%s\n",
+				c.Resources.Requests.Cpu(), c.Resources.Requests.Memory(),
+				c.Resources.Limits.Cpu(), c.Resources.Limits.Memory())
+		}
+		probes := []struct {
+			name  string
+			probe *corev1.Probe
+		}{{"liveness", c.LivenessProbe}, {"readiness", c.ReadinessProbe}, {"startup", c.StartupProbe}}
+		for _, p := range probes {
+			if p.probe != nil {
+				fmt.Fprintf(&b, "    %s probe: %s\n", p.name, events.DescribeProbe(p.probe))
+			}
+		}
+	}
+	return b.String()
+}
+
+func replicas(r *int32) int32 {
+	if r == nil {
+		return 1
+	}
+	return *r
+}
+
+// serviceEndpoints lists the endpoints of a Service from its EndpointSlices
+func (t *clusterTools) serviceEndpoints(ctx context.Context, service string) (string, error) {
+	svc, err := t.clientset.CoreV1().Services(t.namespace).Get(ctx, service, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	slices, err := t.clientset.DiscoveryV1().EndpointSlices(t.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + service,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Service %s: type=%s selector=%v\nPorts:\n", svc.Name, svc.Spec.Type, svc.Spec.Selector)
+	for _, p := range svc.Spec.Ports {
+		fmt.Fprintf(&b, "  - %s %d -> %s/%s\n", p.Name, p.Port, p.TargetPort.String(), p.Protocol)
+	}
+
+	b.WriteString("Endpoints:\n")
+	count := 0
+	for _, slice := range slices.Items {
+		for _, ep := range slice.Endpoints {
+			count++
+			ready := ep.Conditions.Ready == nil || *ep.Conditions.Ready
+			target := ""
+			if ep.TargetRef != nil {
+				target = fmt.Sprintf(" (%s %s)", ep.TargetRef.Kind, ep.TargetRef.Name)
+			}
+			fmt.Fprintf(&b, "  - %s ready=%v%s\n", strings.Join(ep.Addresses, ","), ready, target)
+		}
+	}
+	if count == 0 {
+		b.WriteString("  (none; no pod matches the selector or none is ready)\n")
+	}
+	return b.String(), nil
+}
+
+// nodeConditions describes a node, defaulting to the one running the incident pod
+func (t *clusterTools) nodeConditions(ctx context.Context, nodeName string) (string, error) {
+	if nodeName == "" {
+		pod, err := t.clientset.CoreV1().Pods(t.namespace).Get(ctx, t.podName, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		if pod.Spec.NodeName == "" {
+			return "The incident pod is not scheduled to a node", nil
+		}
+		nodeName = pod.Spec.NodeName
+	}
+
+	node, err := t.clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Node %s: unschedulable=%v kubelet=%s\n", node.Name, node.Spec.Unschedulable, node.Status.NodeInfo.KubeletVersion)
+	fmt.Fprintf(&b, "Allocatable: cpu=%s memory=%s pods=%s\n",
+		node.Status.Allocatable.Cpu(), node.Status.Allocatable.Memory(), node.Status.Allocatable.Pods())
+	b.WriteString("Conditions:\n")
+	for _, c := range node.Status.Conditions {
+		fmt.Fprintf(&b, "  - %s=%s (%s): %s\n", c.Type, c.Status, c.Reason, c.Message)
+	}
+	for _, taint := range node.Spec.Taints {
+		fmt.Fprintf(&b, "Taint: %s\n", taint.ToString())
+	}
+	return b.String(), nil
+}
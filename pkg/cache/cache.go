@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+)
+
+const (
+	// maxBytes bounds the serialized cache so it fits in a ConfigMap (1 MiB)
+	// with room for the object's metadata
+	maxBytes = 900 * 1024
+	// pendingTTL is how long a claim blocks other analyzers before it is ignored
+	pendingTTL = 3 * time.Minute
+)
+
+// Entry is a cached analysis, or a pending claim while the first analyzer of an
+// incident is still waiting for the LLM
+type Entry struct {
+	Analysis  *llm.Analysis `json:"analysis,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Pending   bool          `json:"pending,omitempty"`
+}
+
+// Store persists cache entries across analyzer Jobs
+type Store interface {
+	// Update loads the entries, applies fn and writes them back when fn
+	// reports a change. Implementations retry fn on concurrent writes.
+	Update(ctx context.Context, fn func(entries map[string]*Entry) bool) error
+}
+
+// Cache reuses analyses of incidents with the same fingerprint within a TTL
+type Cache struct {
+	store Store
+	ttl   time.Duration
+}
+
+// New creates a cache over store
+func New(store Store, ttl time.Duration) *Cache {
+	return &Cache{store: store, ttl: ttl}
+}
+
+// Lookup returns the live entry for key, or nil. If another analyzer holds a
+// pending claim, it polls until the analysis arrives or wait elapses.
+func (c *Cache) Lookup(ctx context.Context, key string, wait time.Duration) (*Entry, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		entry, err := c.get(ctx, key)
+		if err != nil || entry == nil || !entry.Pending {
+			return entry, err
+		}
+		if time.Now().After(deadline) {
+			return nil, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// Claim marks key as being analyzed so concurrent analyzers wait for this one.
+// It reports false when another analyzer already holds the claim or a result.
+func (c *Cache) Claim(ctx context.Context, key string) (bool, error) {
+	claimed := false
+	err := c.store.Update(ctx, func(entries map[string]*Entry) bool {
+		if c.live(entries[key], time.Now()) {
+			return false
+		}
+		entries[key] = &Entry{CreatedAt: time.Now(), Pending: true}
+		claimed = true
+		return true
+	})
+	return claimed, err
+}
+
+// Put saves the analysis for key, replacing any claim
+func (c *Cache) Put(ctx context.Context, key string, analysis *llm.Analysis) error {
+	return c.store.Update(ctx, func(entries map[string]*Entry) bool {
+		entries[key] = &Entry{Analysis: analysis, CreatedAt: time.Now()}
+		c.prune(entries)
+		return true
+	})
+}
+
+// Release drops a pending claim after a failed analysis so others stop waiting
+func (c *Cache) Release(ctx context.Context, key string) error {
+	return c.store.Update(ctx, func(entries map[string]*Entry) bool {
+		if entry := entries[key]; entry == nil || !entry.Pending {
+			return false
+		}
+		delete(entries, key)
+		return true
+	})
+}
+
+func (c *Cache) get(ctx context.Context, key string) (*Entry, error) {
+	var found *Entry
+	err := c.store.Update(ctx, func(entries map[string]*Entry) bool {
+		if entry := entries[key]; c.live(entry, time.Now()) {
+			found = entry
+		}
+		return false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read analysis cache: %w", err)
+	}
+	return found, nil
+}
+
+func (c *Cache) live(entry *Entry, now time.Time) bool {
+	if entry == nil {
+		return false
+	}
+	if entry.Pending {
+		return now.Sub(entry.CreatedAt) < pendingTTL
+	}
+	return entry.Analysis != nil && now.Sub(entry.CreatedAt) < c.ttl
+}
+
+// prune drops expired entries and then the oldest until the rest fit in maxBytes
+func (c *Cache) prune(entries map[string]*Entry) {
+	now := time.Now()
+	keys := make([]string, 0, len(entries))
+	sizes := map[string]int{}
+	total := 2
+	for key, entry := range entries {
+		if !c.live(entry, now) {
+			delete(entries, key)
+			continue
+		}
+		keys = append(keys, key)
+		sizes[key] = entrySize(key, entry)
+		total += sizes[key]
+	}
+
+	if total <= maxBytes {
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return entries[keys[i]].CreatedAt.Before(entries[keys[j]].CreatedAt)
+	})
+	for _, key := range keys {
+		if total <= maxBytes {
+			break
+		}
+		delete(entries, key)
+		total -= sizes[key]
+	}
+}
+
+// entrySize is the length of "key":entry, in the JSON object the store writes
+func entrySize(key string, entry *Entry) int {
+	data, err := json.Marshal(map[string]*Entry{key: entry})
+	if err != nil {
+		return 0
+	}
+	// Without the braces, with the separating comma
+	return len(data) - 1
+}
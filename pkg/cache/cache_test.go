@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+)
+
+// memoryStore keeps the entries serialized like the real stores do
+type memoryStore struct {
+	data []byte
+}
+
+func (s *memoryStore) Update(ctx context.Context, fn func(entries map[string]*Entry) bool) error {
+	entries := map[string]*Entry{}
+	if len(s.data) > 0 {
+		if err := json.Unmarshal(s.data, &entries); err != nil {
+			return err
+		}
+	}
+	if !fn(entries) {
+		return nil
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	s.data = data
+	return nil
+}
+
+func TestCacheClaimPutLookup(t *testing.T) {
+	ctx := context.Background()
+	c := New(&memoryStore{}, time.Hour)
+
+	if claimed, err := c.Claim(ctx, "key"); err != nil || !claimed {
+		t.Fatalf("first Claim = %v, %v, want true", claimed, err)
+	}
+	if claimed, err := c.Claim(ctx, "key"); err != nil || claimed {
+		t.Fatalf("second Claim = %v, %v, want false while pending", claimed, err)
+	}
+	if entry, err := c.Lookup(ctx, "key", 0); err != nil || entry != nil {
+		t.Fatalf("Lookup of a pending claim without waiting = %v, %v, want nil", entry, err)
+	}
+
+	if err := c.Put(ctx, "key", &llm.Analysis{RootCause: "bad config"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	entry, err := c.Lookup(ctx, "key", 0)
+	if err != nil || entry == nil || entry.Analysis.RootCause != "bad config" {
+		t.Fatalf("Lookup = %+v, %v, want the stored analysis", entry, err)
+	}
+}
+
+func TestCacheRelease(t *testing.T) {
+	ctx := context.Background()
+	c := New(&memoryStore{}, time.Hour)
+
+	c.Claim(ctx, "key")
+	if err := c.Release(ctx, "key"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if claimed, _ := c.Claim(ctx, "key"); !claimed {
+		t.Error("Claim after Release = false, want true")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := New(&memoryStore{}, time.Hour)
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		entry *Entry
+		live  bool
+	}{
+		{"fresh analysis", &Entry{Analysis: &llm.Analysis{}, CreatedAt: now.Add(-time.Minute)}, true},
+		{"expired analysis", &Entry{Analysis: &llm.Analysis{}, CreatedAt: now.Add(-2 * time.Hour)}, false},
+		{"fresh claim", &Entry{Pending: true, CreatedAt: now.Add(-time.Minute)}, true},
+		{"abandoned claim", &Entry{Pending: true, CreatedAt: now.Add(-pendingTTL - time.Second)}, false},
+		{"missing", nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := c.live(tt.entry, now); got != tt.live {
+			t.Errorf("%s: live = %v, want %v", tt.name, got, tt.live)
+		}
+	}
+}
+
+func TestCachePruneBySize(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryStore{}
+	c := New(store, time.Hour)
+
+	// Large analyses: far fewer than the old 200-entry limit already fill a ConfigMap
+	rootCause := strings.Repeat("x", 20*1024)
+	for i := 0; i < 100; i++ {
+		if err := c.Put(ctx, fmt.Sprintf("key-%03d", i), &llm.Analysis{RootCause: rootCause}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	if len(store.data) > maxBytes {
+		t.Errorf("cache is %d bytes, want at most %d", len(store.data), maxBytes)
+	}
+	if entry, _ := c.Lookup(ctx, "key-099", 0); entry == nil {
+		t.Error("newest entry was pruned")
+	}
+	if entry, _ := c.Lookup(ctx, "key-000", 0); entry != nil {
+		t.Error("oldest entry was kept")
+	}
+}
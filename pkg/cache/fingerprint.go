@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// signatureLines is how many trailing log lines make up the log signature;
+// the end of the log is where crashes leave their error
+const signatureLines = 20
+
+// Incident holds the inputs of a fingerprint
+type Incident struct {
+	EventType string
+	// Namespace keeps the same failure in different tenants' namespaces apart
+	Namespace string
+	Image     string
+	ExitCode  int32
+	Logs      string
+	// PodName is removed from the logs so replicas of one workload match
+	PodName string
+}
+
+// volatile matches values that differ between otherwise identical failures.
+// Order matters: timestamps and UUIDs go before the bare numbers inside them.
+var volatile = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`), "<ts>"},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\b`), "<ts>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<uuid>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b`), "<hex>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{12,}\b`), "<hex>"},
+	{regexp.MustCompile(`\d+`), "<n>"},
+}
+
+// Fingerprint returns a stable key for an incident: event type, namespace,
+// image, exit code and the last log lines with timestamps, UUIDs, IDs and
+// numbers removed
+func Fingerprint(in Incident) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%s", in.EventType, in.Namespace, in.Image, in.ExitCode, LogSignature(in.Logs, in.PodName))))
+	return hex.EncodeToString(sum[:16])
+}
+
+// LogSignature normalizes the last non-empty log lines
+func LogSignature(logs, podName string) string {
+	lines := strings.Split(logs, "\n")
+	var sig []string
+	for i := len(lines) - 1; i >= 0 && len(sig) < signatureLines; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+		if podName != "" {
+			line = strings.ReplaceAll(line, podName, "<pod>")
+		}
+		for _, v := range volatile {
+			line = v.re.ReplaceAllString(line, v.repl)
+		}
+		sig = append(sig, line)
+	}
+
+	// Restore chronological order
+	for i, j := 0, len(sig)-1; i < j; i, j = i+1, j-1 {
+		sig[i], sig[j] = sig[j], sig[i]
+	}
+	return strings.Join(sig, "\n")
+}
@@ -0,0 +1,51 @@
+package cache
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	base := Incident{
+		EventType: "CrashLoopBackOff",
+		Namespace: "team-a",
+		Image:     "registry.internal/api:1.4.2",
+		ExitCode:  1,
+		Logs:      "2024-01-02T15:04:05Z api-7d9f-x2x starting\n2024-01-02T15:04:06Z dial tcp 10.0.0.12:5432: connection refused",
+		PodName:   "api-7d9f-x2x",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(in *Incident)
+		same   bool
+	}{
+		{"identical", func(in *Incident) {}, true},
+		{"other replica, time and address", func(in *Incident) {
+			in.PodName = "api-7d9f-q8w"
+			in.Logs = "2024-03-04T09:00:00Z api-7d9f-q8w starting\n2024-03-04T09:00:01Z dial tcp 10.0.0.47:5432: connection refused"
+		}, true},
+		{"other namespace", func(in *Incident) { in.Namespace = "team-b" }, false},
+		{"other event type", func(in *Incident) { in.EventType = "OOMKilled" }, false},
+		{"other image", func(in *Incident) { in.Image = "registry.internal/api:1.5.0" }, false},
+		{"other exit code", func(in *Incident) { in.ExitCode = 137 }, false},
+		{"other error", func(in *Incident) {
+			in.Logs = "2024-01-02T15:04:05Z api-7d9f-x2x starting\n2024-01-02T15:04:06Z missing env DATABASE_URL"
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base
+			tt.modify(&other)
+			if same := Fingerprint(base) == Fingerprint(other); same != tt.same {
+				t.Errorf("fingerprints equal = %v, want %v", same, tt.same)
+			}
+		})
+	}
+}
+
+func TestLogSignature(t *testing.T) {
+	logs := "\n2024-01-02 15:04:05,123 worker-1 request 8d3f2a1c-1b2c-4d5e-8f90-123456789abc failed after 350ms at 0xc000123\n\n"
+	want := "<ts> worker-<n> request <uuid> failed after <n>ms at <hex>"
+	if got := LogSignature(logs, ""); got != want {
+		t.Errorf("LogSignature() = %q, want %q", got, want)
+	}
+}
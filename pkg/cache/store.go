@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/store"
+	"k8s.io/client-go/kubernetes"
+)
+
+// entriesKey is the ConfigMap data key holding the JSON-encoded entries
+const entriesKey = "entries.json"
+
+// NewFileStore creates a store backed by the file at path, e.g. on a volume
+// shared by analyzer Jobs
+func NewFileStore(path string) Store {
+	return entryStore{store.NewFileStore[map[string]*Entry](path, "cache")}
+}
+
+// NewConfigMapStore creates a store backed by the named ConfigMap
+func NewConfigMapStore(clientset *kubernetes.Clientset, namespace, name string) Store {
+	return entryStore{store.NewConfigMapStore[map[string]*Entry](clientset, namespace, name, entriesKey, "cache")}
+}
+
+// entryStore adapts a JSON store to Store, which updates the entries in place
+type entryStore struct {
+	store store.Store[map[string]*Entry]
+}
+
+// Update implements Store
+func (s entryStore) Update(ctx context.Context, fn func(entries map[string]*Entry) bool) error {
+	return s.store.Update(ctx, func(entries map[string]*Entry) (map[string]*Entry, bool) {
+		if entries == nil {
+			entries = map[string]*Entry{}
+		}
+		return entries, fn(entries)
+	})
+}
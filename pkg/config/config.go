@@ -8,25 +8,283 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	Events   EventsConfig   `yaml:"events"`
-	LLM      LLMConfig      `yaml:"llm"`
-	Slack    SlackConfig    `yaml:"slack"`
-	Analyzer AnalyzerConfig `yaml:"analyzer"`
+	Events    EventsConfig    `yaml:"events"`
+	LLM       LLMConfig       `yaml:"llm"`
+	Slack     SlackConfig     `yaml:"slack"`
+	Analyzer  AnalyzerConfig  `yaml:"analyzer"`
+	Prompts   PromptsConfig   `yaml:"prompts"`
+	Redaction RedactionConfig `yaml:"redaction"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Knowledge KnowledgeConfig `yaml:"knowledge"`
+	Runbooks  RunbooksConfig  `yaml:"runbooks"`
+	Injection InjectionConfig `yaml:"injection"`
+	Commands  CommandsConfig  `yaml:"commands"`
+	Logs      LogsConfig      `yaml:"logs"`
 }
 
 // EventsConfig defines which events to monitor
 type EventsConfig struct {
-	CrashLoopBackOff  bool `yaml:"crashLoopBackOff"`
-	ImagePullBackOff  bool `yaml:"imagePullBackOff"`
+	CrashLoopBackOff   bool `yaml:"crashLoopBackOff"`
+	ImagePullBackOff   bool `yaml:"imagePullBackOff"`
 	HealthCheckFailure bool `yaml:"healthCheckFailure"`
-	OOMKilled         bool `yaml:"oomKilled"`
+	OOMKilled          bool `yaml:"oomKilled"`
+	// EventReasons turns Kubernetes Events into incidents, for failures that never
+	// show up in a container status (scheduling, volumes, pod sandboxes)
+	EventReasons []EventReasonConfig `yaml:"eventReasons"`
+}
+
+// EventReasonConfig maps Kubernetes Events with a reason to an incident type
+type EventReasonConfig struct {
+	Reason string `yaml:"reason"`
+	// MessagePattern is a regular expression the event message must match, e.g.
+	// to tell image pull back-offs from container restart back-offs
+	MessagePattern string `yaml:"messagePattern"`
+	EventType      string `yaml:"eventType"`
+	// MinCount ignores events reported fewer times, e.g. FailedScheduling while
+	// the cluster autoscaler adds a node
+	MinCount int32 `yaml:"minCount"`
 }
 
 // LLMConfig contains LLM provider settings
 type LLMConfig struct {
-	Provider  string            `yaml:"provider"`
-	Model     map[string]string `yaml:"model"`
-	MaxTokens int               `yaml:"maxTokens"`
+	Provider       string            `yaml:"provider"`
+	Model          map[string]string `yaml:"model"`
+	MaxTokens      int               `yaml:"maxTokens"`
+	ContextTokens  int               `yaml:"contextTokens"`
+	BaseURL        string            `yaml:"baseURL"`
+	AuthHeader     string            `yaml:"authHeader"`
+	OpenAI         OpenAIConfig      `yaml:"openai"`
+	TimeoutSeconds int               `yaml:"timeoutSeconds"`
+	MaxRetries     int               `yaml:"maxRetries"`
+	// Providers is an ordered fallback chain; when set it takes precedence over Provider
+	Providers []ProviderConfig `yaml:"providers"`
+	Agent     AgentConfig      `yaml:"agent"`
+	// Pricing maps a model name (or prefix) to its price, used for cost accounting
+	Pricing map[string]ModelPrice `yaml:"pricing"`
+	Budget  BudgetConfig          `yaml:"budget"`
+	Mock    MockConfig            `yaml:"mock"`
+	Record  RecordConfig          `yaml:"record"`
+	Tiering TieringConfig         `yaml:"tiering"`
+	// Routes pick a provider/model per incident; the first matching route wins
+	Routes    []RouteConfig   `yaml:"routes"`
+	Consensus ConsensusConfig `yaml:"consensus"`
+}
+
+// ConsensusConfig samples several analyses of severe incidents and reports the
+// root cause most of them agree on
+type ConsensusConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Samples is the total number of analyses, including the first (default 3)
+	Samples int `yaml:"samples"`
+	// Severities that trigger sampling (default high and critical)
+	Severities []string `yaml:"severities"`
+	// Providers to draw the extra samples from in turn; empty uses the configured ones
+	Providers []string `yaml:"providers"`
+	// MinAgreement below which the samples are reported as disagreeing (default 0.5)
+	MinAgreement float64 `yaml:"minAgreement"`
+}
+
+// RouteConfig matches incidents by event type, namespace and pod labels. Empty
+// matchers match everything; empty settings keep the defaults.
+type RouteConfig struct {
+	Name       string            `yaml:"name"`
+	EventTypes []string          `yaml:"eventTypes"`
+	Namespaces []string          `yaml:"namespaces"`
+	Labels     map[string]string `yaml:"labels"`
+	Provider   string            `yaml:"provider"`
+	Model      string            `yaml:"model"`
+	MaxTokens  int               `yaml:"maxTokens"`
+}
+
+// TieringConfig sends incidents to a fast triage model first and escalates to
+// the regular model when the triage is unsure or the incident is severe
+type TieringConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TriageModel maps a provider name to its triage model, like LLMConfig.Model
+	TriageModel map[string]string `yaml:"triageModel"`
+	// ConfidenceThreshold escalates triage results below it (default 0.7)
+	ConfidenceThreshold float64 `yaml:"confidenceThreshold"`
+	// EscalateSeverities always escalates these severities (e.g. high, critical)
+	EscalateSeverities []string `yaml:"escalateSeverities"`
+}
+
+// MockConfig holds the canned output of the mock provider, keyed by event type or "default"
+type MockConfig struct {
+	// FixturesDir contains <EventType>.json and default.json files
+	FixturesDir string            `yaml:"fixturesDir"`
+	Responses   map[string]string `yaml:"responses"`
+}
+
+// RecordConfig saves provider responses to disk or replays them offline
+type RecordConfig struct {
+	// Mode is "record", "replay" or empty
+	Mode string `yaml:"mode"`
+	Dir  string `yaml:"dir"`
+}
+
+// ModelPrice is the USD price per million tokens
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// BudgetConfig caps LLM spend; once a cap is reached incidents are notified without analysis
+type BudgetConfig struct {
+	// DailyUSD and MonthlyUSD are calendar periods in UTC; 0 disables the cap
+	DailyUSD   float64 `yaml:"dailyUSD"`
+	MonthlyUSD float64 `yaml:"monthlyUSD"`
+}
+
+// AgentConfig enables the multi-turn mode in which the model may call read-only
+// cluster tools before answering
+type AgentConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxSteps caps the model turns that may call tools
+	MaxSteps int `yaml:"maxSteps"`
+	// MaxTokens is a hard cap on input plus output tokens across all turns
+	MaxTokens int `yaml:"maxTokens"`
+}
+
+// ProviderConfig is one entry of the provider fallback chain
+type ProviderConfig struct {
+	Name         string       `yaml:"name"`
+	Model        string       `yaml:"model"`
+	MaxTokens    int          `yaml:"maxTokens"`
+	BaseURL      string       `yaml:"baseURL"`
+	AuthHeader   string       `yaml:"authHeader"`
+	APIKeySecret SecretKeyRef `yaml:"apiKeySecret"`
+}
+
+// SecretKeyRef references a key in a Secret in the agent namespace
+type SecretKeyRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+// OpenAIConfig contains OpenAI-specific settings
+type OpenAIConfig struct {
+	Organization string `yaml:"organization"`
+	Project      string `yaml:"project"`
+}
+
+// PromptsConfig overrides the built-in prompt templates (Go text/template syntax)
+type PromptsConfig struct {
+	System string `yaml:"system"`
+	// Default is the user prompt template for event types without their own template
+	Default string `yaml:"default"`
+	// EventTypes maps an event type (e.g. OOMKilled) to its user prompt template
+	EventTypes map[string]string `yaml:"eventTypes"`
+	// Guidance maps an event type to the advice rendered by {{ guidance }}
+	Guidance map[string]string `yaml:"guidance"`
+}
+
+// RedactionConfig controls masking of secrets and PII before context leaves the cluster
+type RedactionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// DisabledDetectors turns off built-in detectors by name (e.g. ipv4, email)
+	DisabledDetectors []string           `yaml:"disabledDetectors"`
+	Patterns          []RedactionPattern `yaml:"patterns"`
+}
+
+// InjectionConfig controls the prompt-injection checks. Untrusted context is
+// always fenced; detection flags suspicious lines and the output check rejects
+// analyses that were likely steered by them.
+type InjectionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Patterns are extra regular expressions flagged as injection attempts
+	Patterns []string `yaml:"patterns"`
+	// RejectUnsafeOutput withholds analyses that fail the output check
+	RejectUnsafeOutput bool `yaml:"rejectUnsafeOutput"`
+	// AllowedDomains may be linked from an analysis without appearing in the context
+	AllowedDomains []string `yaml:"allowedDomains"`
+}
+
+// CommandsConfig controls the checks of kubectl commands suggested by the model
+type CommandsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Destructive is "annotate" (default) or "strip"
+	Destructive string `yaml:"destructive"`
+	// VerifyResources flags commands naming resources that do not exist
+	VerifyResources bool `yaml:"verifyResources"`
+}
+
+// RedactionPattern is a user-defined redaction regex. If it has a group named
+// "secret" only that group is masked.
+type RedactionPattern struct {
+	Name  string `yaml:"name"`
+	Regex string `yaml:"regex"`
+	// Restore puts the original value back into the analysis shown in notifications
+	Restore bool `yaml:"restore"`
+}
+
+// CacheConfig reuses the analysis of an identical incident (same fingerprint)
+// across analyzer jobs
+type CacheConfig struct {
+	Enabled    bool `yaml:"enabled"`
+	TTLMinutes int  `yaml:"ttlMinutes"`
+	// Backend is "configmap" (default) or "file"
+	Backend   string `yaml:"backend"`
+	ConfigMap string `yaml:"configMap"`
+	// Path is the cache file of the file backend, on a shared volume
+	Path string `yaml:"path"`
+	// PersistentVolumeClaim is mounted read-write at the directory of Path in
+	// analyzer jobs; the file backend requires it
+	PersistentVolumeClaim string `yaml:"persistentVolumeClaim"`
+	// WaitSeconds is how long to wait for a concurrent analysis of the same incident
+	WaitSeconds int `yaml:"waitSeconds"`
+}
+
+// KnowledgeConfig stores analyzed incidents and adds the most similar past
+// incidents to the prompt
+type KnowledgeConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Backend is "configmap" (default) or "file"
+	Backend   string `yaml:"backend"`
+	ConfigMap string `yaml:"configMap"`
+	// Path is the knowledge base file of the file backend, on a PVC
+	Path string `yaml:"path"`
+	// PersistentVolumeClaim is mounted read-write at the directory of Path in
+	// analyzer jobs; the file backend requires it
+	PersistentVolumeClaim string `yaml:"persistentVolumeClaim"`
+	// MaxIncidents bounds the stored incidents; the least recently seen are dropped
+	MaxIncidents int `yaml:"maxIncidents"`
+	// TopK is how many similar incidents are added to the prompt (default 3)
+	TopK int `yaml:"topK"`
+	// MinSimilarity from 0 to 1 filters out unrelated incidents (default 0.3)
+	MinSimilarity float64 `yaml:"minSimilarity"`
+}
+
+// RunbooksConfig adds the runbook sections most relevant to the incident to the
+// prompt and the notification
+type RunbooksConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Dir holds Markdown runbooks, e.g. a Git-synced volume
+	Dir string `yaml:"dir"`
+	// PersistentVolumeClaim is mounted read-only at Dir in analyzer jobs when set
+	PersistentVolumeClaim string `yaml:"persistentVolumeClaim"`
+	// ConfigMapSelector selects ConfigMaps in the agent namespace whose .md keys are runbooks
+	ConfigMapSelector string `yaml:"configMapSelector"`
+	// TopK is how many runbook sections are included (default 3)
+	TopK int `yaml:"topK"`
+	// MinSimilarity from 0 to 1 filters out unrelated sections (default 0.2)
+	MinSimilarity float64 `yaml:"minSimilarity"`
+}
+
+// LogsConfig controls how much container log is fetched and how it is condensed
+// for the prompt
+type LogsConfig struct {
+	// TailLines is how many lines are fetched per container (default 100, or
+	// 1000 with Compress)
+	TailLines int64 `yaml:"tailLines"`
+	// Compress groups repeated lines into templates with a count, the first and
+	// last timestamps and one example, so far more lines fit the prompt
+	Compress bool `yaml:"compress"`
+	// StackTraces extracts panics and exceptions from the logs into a section
+	// that is never truncated
+	StackTraces bool `yaml:"stackTraces"`
+	// StackTraceLines is how many lines per container are searched for stack
+	// traces (default 5000), so a trace that starts above the tail is still found
+	StackTraceLines int64 `yaml:"stackTraceLines"`
 }
 
 // SlackConfig contains Slack notification settings
@@ -42,6 +300,8 @@ type AnalyzerConfig struct {
 	BackoffLimit            int32           `yaml:"backoffLimit"`
 	ActiveDeadlineSeconds   int64           `yaml:"activeDeadlineSeconds"`
 	Resources               ResourcesConfig `yaml:"resources"`
+	// ConfigMap holding this configuration; mounted into analyzer jobs when set
+	ConfigMap string `yaml:"configMap"`
 }
 
 // ResourcesConfig defines resource requests and limits
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// budgetConfigMap persists spend across controller restarts
+	budgetConfigMap = "kube-ai-sre-agent-usage"
+	budgetStateKey  = "usage.json"
+	// recordedRetention bounds the persisted set of counted pods; analyzer pods
+	// are deleted long before (Job ttlSecondsAfterFinished)
+	recordedRetention = 7 * 24 * time.Hour
+)
+
+// budgetState is the spend of the current UTC day and month
+type budgetState struct {
+	Day          string  `json:"day"`
+	DayCostUSD   float64 `json:"dayCostUSD"`
+	DayTokens    int     `json:"dayTokens"`
+	Month        string  `json:"month"`
+	MonthCostUSD float64 `json:"monthCostUSD"`
+	MonthTokens  int     `json:"monthTokens"`
+	// Recorded holds the analyzer pods already counted and when, so pods the
+	// informer replays after a restart are not charged twice
+	Recorded map[types.UID]time.Time `json:"recorded,omitempty"`
+}
+
+// BudgetTracker accumulates the LLM usage reported by analyzer jobs and tells
+// whether the daily or monthly spend cap has been reached. Jobs already running
+// when a cap is crossed still complete, so spend can overshoot by that much.
+type BudgetTracker struct {
+	mu        sync.Mutex
+	budget    config.BudgetConfig
+	state     budgetState
+	clientset *kubernetes.Clientset
+	namespace string
+}
+
+// NewBudgetTracker creates a tracker persisting its state in the given namespace
+func NewBudgetTracker(clientset *kubernetes.Clientset, namespace string, budget config.BudgetConfig) *BudgetTracker {
+	return &BudgetTracker{
+		budget:    budget,
+		state:     budgetState{Recorded: map[types.UID]time.Time{}},
+		clientset: clientset,
+		namespace: namespace,
+	}
+}
+
+// Load restores the spend and the counted pods recorded before a restart
+func (b *BudgetTracker) Load(ctx context.Context) error {
+	cm, err := b.clientset.CoreV1().ConfigMaps(b.namespace).Get(ctx, budgetConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get usage configmap: %w", err)
+	}
+
+	var state budgetState
+	if err := json.Unmarshal([]byte(cm.Data[budgetStateKey]), &state); err != nil {
+		return fmt.Errorf("failed to parse usage configmap: %w", err)
+	}
+
+	if state.Recorded == nil {
+		state.Recorded = map[types.UID]time.Time{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = state
+	b.roll(time.Now())
+	return nil
+}
+
+// Exceeded returns why analysis should be skipped, or "" while within budget
+func (b *BudgetTracker) Exceeded() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(time.Now())
+
+	if b.budget.DailyUSD > 0 && b.state.DayCostUSD >= b.budget.DailyUSD {
+		return fmt.Sprintf("daily LLM budget of $%.2f reached ($%.2f spent)", b.budget.DailyUSD, b.state.DayCostUSD)
+	}
+	if b.budget.MonthlyUSD > 0 && b.state.MonthCostUSD >= b.budget.MonthlyUSD {
+		return fmt.Sprintf("monthly LLM budget of $%.2f reached ($%.2f spent)", b.budget.MonthlyUSD, b.state.MonthCostUSD)
+	}
+	return ""
+}
+
+// RecordPod adds the usage an analyzer pod wrote to its termination message.
+// Each pod is counted once, also across controller restarts.
+func (b *BudgetTracker) RecordPod(ctx context.Context, pod *corev1.Pod) {
+	if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+		return
+	}
+
+	var usage llm.Usage
+	found := false
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Terminated == nil || cs.State.Terminated.Message == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(cs.State.Terminated.Message), &usage); err != nil {
+			klog.Warningf("Failed to parse usage reported by %s/%s: %v", pod.Namespace, pod.Name, err)
+			return
+		}
+		found = true
+	}
+	if !found {
+		return
+	}
+
+	b.mu.Lock()
+	if _, ok := b.state.Recorded[pod.UID]; ok {
+		b.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	b.state.Recorded[pod.UID] = now
+	b.roll(now)
+	b.state.DayCostUSD += usage.CostUSD
+	b.state.DayTokens += usage.Total()
+	b.state.MonthCostUSD += usage.CostUSD
+	b.state.MonthTokens += usage.Total()
+	state := b.snapshot(now)
+	b.mu.Unlock()
+
+	klog.Infof("Analysis %s used %d tokens of %s ($%.4f); spent today $%.4f, this month $%.4f",
+		pod.Name, usage.Total(), usage.Model, usage.CostUSD, state.DayCostUSD, state.MonthCostUSD)
+
+	if err := b.save(ctx, state); err != nil {
+		klog.Warningf("Failed to persist LLM usage: %v", err)
+	}
+}
+
+// Forget drops a deleted pod from the set of recorded pods; a deleted pod
+// cannot be replayed, so the next save no longer persists it
+func (b *BudgetTracker) Forget(uid types.UID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.state.Recorded, uid)
+}
+
+// snapshot copies the state for saving outside the lock, dropping counted pods
+// older than recordedRetention, e.g. deleted while the controller was down
+func (b *BudgetTracker) snapshot(now time.Time) budgetState {
+	state := b.state
+	state.Recorded = make(map[types.UID]time.Time, len(b.state.Recorded))
+	for uid, at := range b.state.Recorded {
+		if now.Sub(at) > recordedRetention {
+			delete(b.state.Recorded, uid)
+			continue
+		}
+		state.Recorded[uid] = at
+	}
+	return state
+}
+
+// roll starts a new day or month when the UTC calendar has moved on
+func (b *BudgetTracker) roll(now time.Time) {
+	now = now.UTC()
+	if day := now.Format("2006-01-02"); b.state.Day != day {
+		b.state.Day, b.state.DayCostUSD, b.state.DayTokens = day, 0, 0
+	}
+	if month := now.Format("2006-01"); b.state.Month != month {
+		b.state.Month, b.state.MonthCostUSD, b.state.MonthTokens = month, 0, 0
+	}
+}
+
+func (b *BudgetTracker) save(ctx context.Context, state budgetState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	configMaps := b.clientset.CoreV1().ConfigMaps(b.namespace)
+	cm, err := configMaps.Get(ctx, budgetConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      budgetConfigMap,
+				Namespace: b.namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/name": "kube-ai-sre-agent",
+				},
+			},
+			Data: map[string]string{budgetStateKey: string(data)},
+		}
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[budgetStateKey] = string(data)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
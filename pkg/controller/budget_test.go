@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// configMapServer is a stub API server holding ConfigMaps in memory, enough
+// for the get, create and update calls of the budget tracker
+type configMapServer struct {
+	mu         sync.Mutex
+	configMaps map[string]*corev1.ConfigMap
+}
+
+func newTestClientset(t *testing.T) (*kubernetes.Clientset, *configMapServer) {
+	t.Helper()
+	s := &configMapServer{configMaps: map[string]*corev1.ConfigMap{}}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create clientset: %v", err)
+	}
+	return clientset, s
+}
+
+func (s *configMapServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// /api/v1/namespaces/<ns>/configmaps[/<name>]
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 5 || parts[4] != "configmaps" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		cm, ok := s.configMaps[parts[3]+"/"+parts[len(parts)-1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonNotFound,
+				Code:     http.StatusNotFound,
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(cm)
+	case http.MethodPost, http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		cm := &corev1.ConfigMap{}
+		if err := json.Unmarshal(data, cm); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.configMaps[parts[3]+"/"+cm.Name] = cm
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(cm)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// analyzerPod is a finished analyzer pod reporting usage in its termination message
+func analyzerPod(uid string, usage llm.Usage) *corev1.Pod {
+	message, _ := json.Marshal(usage)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "analyzer-" + uid, Namespace: "sre", UID: types.UID(uid)},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodSucceeded,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: string(message)}},
+			}},
+		},
+	}
+}
+
+func TestBudgetTrackerRecordPod(t *testing.T) {
+	ctx := context.Background()
+	clientset, _ := newTestClientset(t)
+	budget := config.BudgetConfig{DailyUSD: 1, MonthlyUSD: 10}
+
+	tracker := NewBudgetTracker(clientset, "sre", budget)
+	if err := tracker.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	pod := analyzerPod("a", llm.Usage{Model: "gpt-4o", InputTokens: 1000, OutputTokens: 200, CostUSD: 0.6})
+	tracker.RecordPod(ctx, pod)
+	tracker.RecordPod(ctx, pod)
+	if tracker.state.DayCostUSD != 0.6 || tracker.state.DayTokens != 1200 {
+		t.Errorf("day spend = $%v / %d tokens, want one pod counted once", tracker.state.DayCostUSD, tracker.state.DayTokens)
+	}
+	if reason := tracker.Exceeded(); reason != "" {
+		t.Errorf("Exceeded() = %q within budget", reason)
+	}
+
+	// A restarted controller sees the same pod replayed by the informer
+	restarted := NewBudgetTracker(clientset, "sre", budget)
+	if err := restarted.Load(ctx); err != nil {
+		t.Fatalf("Load after restart: %v", err)
+	}
+	restarted.RecordPod(ctx, pod)
+	if restarted.state.DayCostUSD != 0.6 {
+		t.Errorf("day spend after restart = $%v, want the replayed pod not charged again", restarted.state.DayCostUSD)
+	}
+
+	restarted.RecordPod(ctx, analyzerPod("b", llm.Usage{CostUSD: 0.5}))
+	if reason := restarted.Exceeded(); !strings.Contains(reason, "daily LLM budget") {
+		t.Errorf("Exceeded() = %q, want the daily cap reached", reason)
+	}
+}
+
+func TestBudgetTrackerIgnoresPods(t *testing.T) {
+	running := analyzerPod("running", llm.Usage{CostUSD: 1})
+	running.Status.Phase = corev1.PodRunning
+	noUsage := analyzerPod("silent", llm.Usage{})
+	noUsage.Status.ContainerStatuses[0].State.Terminated.Message = ""
+
+	clientset, _ := newTestClientset(t)
+	tracker := NewBudgetTracker(clientset, "sre", config.BudgetConfig{})
+	for _, pod := range []*corev1.Pod{running, noUsage} {
+		tracker.RecordPod(context.Background(), pod)
+	}
+	if len(tracker.state.Recorded) != 0 || tracker.state.DayCostUSD != 0 {
+		t.Errorf("state = %+v, want unfinished and silent pods ignored", tracker.state)
+	}
+}
+
+func TestBudgetTrackerRoll(t *testing.T) {
+	tracker := NewBudgetTracker(nil, "sre", config.BudgetConfig{})
+	tracker.state = budgetState{
+		Day: "2026-01-31", DayCostUSD: 2, DayTokens: 10,
+		Month: "2026-01", MonthCostUSD: 20, MonthTokens: 100,
+	}
+
+	tracker.roll(time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC))
+	if tracker.state.DayCostUSD != 2 || tracker.state.MonthCostUSD != 20 {
+		t.Errorf("state rolled within the same day: %+v", tracker.state)
+	}
+
+	tracker.roll(time.Date(2026, 2, 1, 0, 30, 0, 0, time.UTC))
+	if tracker.state.Day != "2026-02-01" || tracker.state.DayCostUSD != 0 || tracker.state.Month != "2026-02" || tracker.state.MonthCostUSD != 0 {
+		t.Errorf("state not rolled into the new month: %+v", tracker.state)
+	}
+}
+
+func TestBudgetTrackerSnapshotPrunesRecorded(t *testing.T) {
+	now := time.Now()
+	tracker := NewBudgetTracker(nil, "sre", config.BudgetConfig{})
+	tracker.state.Recorded = map[types.UID]time.Time{
+		"recent": now.Add(-time.Hour),
+		"old":    now.Add(-recordedRetention - time.Hour),
+	}
+
+	state := tracker.snapshot(now)
+	if _, ok := state.Recorded["old"]; ok {
+		t.Error("pod counted before the retention period was kept")
+	}
+	if _, ok := state.Recorded["recent"]; !ok {
+		t.Error("recently counted pod was dropped")
+	}
+
+	tracker.Forget("recent")
+	if len(tracker.state.Recorded) != 0 {
+		t.Errorf("Recorded = %v after Forget, want empty", tracker.state.Recorded)
+	}
+}
+
+func TestAnalyzerPodHandlers(t *testing.T) {
+	ctx := context.Background()
+	clientset, _ := newTestClientset(t)
+	c := &Controller{budget: NewBudgetTracker(clientset, "sre", config.BudgetConfig{})}
+	if err := c.budget.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Listed on startup after finishing while the controller was down
+	pod := analyzerPod("a", llm.Usage{CostUSD: 0.25})
+	c.handleAnalyzerPodAdd(pod)
+	if c.budget.state.DayCostUSD != 0.25 {
+		t.Errorf("day spend = $%v after the initial list, want the finished pod counted", c.budget.state.DayCostUSD)
+	}
+
+	c.handleAnalyzerPodUpdate(pod, pod)
+	if c.budget.state.DayCostUSD != 0.25 {
+		t.Errorf("day spend = $%v after a resync, want the pod counted once", c.budget.state.DayCostUSD)
+	}
+
+	c.handleAnalyzerPodDelete(pod)
+	if _, ok := c.budget.state.Recorded[pod.UID]; ok {
+		t.Error("deleted pod still recorded")
+	}
+}
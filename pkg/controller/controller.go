@@ -3,26 +3,39 @@ package controller
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
 	"github.com/adiii717/kube-ai-sre-agent/pkg/events"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 )
 
+const (
+	// analyzerConfigDir is where the configuration ConfigMap is mounted in analyzer jobs
+	analyzerConfigDir = "/etc/config"
+	// maxEventAge skips events replayed by the informer that no longer describe
+	// the current state, e.g. after a controller restart
+	maxEventAge = 5 * time.Minute
+)
+
 // Controller watches pods and spawns analysis jobs
 type Controller struct {
 	clientset      *kubernetes.Clientset
 	config         *config.Config
 	detector       *events.Detector
 	tracker        *IncidentTracker
+	budget         *BudgetTracker
+	pods           corelisters.PodLister
 	namespace      string
 	watchNamespace string
 	llmAPIKey      string
@@ -36,6 +49,7 @@ func New(clientset *kubernetes.Clientset, cfg *config.Config, namespace, watchNa
 		config:         cfg,
 		detector:       events.NewDetector(&cfg.Events),
 		tracker:        NewIncidentTracker(cooldown, escalationEnabled, escalationThreshold, silenceDuration),
+		budget:         NewBudgetTracker(clientset, namespace, cfg.LLM.Budget),
 		namespace:      namespace,
 		watchNamespace: watchNamespace,
 		llmAPIKey:      llmAPIKey,
@@ -67,12 +81,49 @@ func (c *Controller) Run(ctx context.Context) error {
 	podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		UpdateFunc: c.handlePodUpdate,
 	})
+	c.pods = factory.Core().V1().Pods().Lister()
+	synced := []cache.InformerSynced{podInformer.HasSynced}
+
+	// Probe failures, scheduling and volume problems are only visible as events
+	if c.detector.WatchesEvents() {
+		eventInformer := factory.Core().V1().Events().Informer()
+		eventInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.handleEvent,
+			UpdateFunc: func(oldObj, newObj interface{}) { c.handleEvent(newObj) },
+		})
+		synced = append(synced, eventInformer.HasSynced)
+	}
+
+	// Restore LLM spend recorded before a restart
+	if err := c.budget.Load(ctx); err != nil {
+		klog.Warningf("Failed to load LLM usage, budgets start from zero: %v", err)
+	}
 
-	// Start informer
+	// Watch analyzer pods for the token usage they report on exit
+	analyzerFactory := informers.NewSharedInformerFactoryWithOptions(
+		c.clientset,
+		time.Minute,
+		informers.WithNamespace(c.namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = "app.kubernetes.io/component=analyzer"
+		}),
+	)
+	analyzerInformer := analyzerFactory.Core().V1().Pods().Informer()
+	// Adds cover pods that finished while the controller was down; the informer
+	// lists them on startup and the tracker skips those already counted
+	analyzerInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.handleAnalyzerPodAdd,
+		UpdateFunc: c.handleAnalyzerPodUpdate,
+		DeleteFunc: c.handleAnalyzerPodDelete,
+	})
+
+	// Start informers
 	factory.Start(ctx.Done())
+	analyzerFactory.Start(ctx.Done())
 
 	// Wait for cache sync
-	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
+	synced = append(synced, analyzerInformer.HasSynced)
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
 		return fmt.Errorf("failed to sync cache")
 	}
 
@@ -100,7 +151,54 @@ func (c *Controller) handlePodUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
-	klog.Infof("Detected %s for pod %s/%s", incident.EventType, incident.Namespace, incident.PodName)
+	c.report(incident, pod)
+}
+
+func (c *Controller) handleEvent(obj interface{}) {
+	event, ok := obj.(*corev1.Event)
+	if !ok {
+		return
+	}
+	if time.Since(eventTime(event)) > maxEventAge {
+		return
+	}
+
+	// Resolve the involved pod; events about other objects become incidents of their own
+	object := event.InvolvedObject
+	var pod *corev1.Pod
+	if object.Kind == "Pod" {
+		var err error
+		if pod, err = c.pods.Pods(object.Namespace).Get(object.Name); err != nil {
+			klog.V(2).Infof("Ignoring %s event for pod %s/%s: %v", event.Reason, object.Namespace, object.Name, err)
+			return
+		}
+		if pod.Labels != nil && pod.Labels["app.kubernetes.io/component"] == "analyzer" {
+			return
+		}
+		if object.UID != "" && pod.UID != object.UID {
+			// A newer pod with the same name (StatefulSet); the event is stale
+			return
+		}
+	}
+
+	incident := c.detector.DetectEvent(event, pod)
+	if incident == nil {
+		return
+	}
+	c.report(incident, pod)
+}
+
+// report spawns an analysis job for a detected incident unless it was analyzed
+// recently. pod is nil for incidents about other objects.
+func (c *Controller) report(incident *events.PodIncident, pod *corev1.Pod) {
+	if incident.Kind != "" {
+		klog.Infof("Detected %s for %s %s/%s", incident.EventType, incident.Kind, incident.Namespace, incident.PodName)
+	} else {
+		klog.Infof("Detected %s for pod %s/%s", incident.EventType, incident.Namespace, incident.PodName)
+	}
+	if pod != nil {
+		incident.Labels = pod.Labels
+	}
 
 	// Check if we should analyze (deduplication)
 	if !c.tracker.ShouldAnalyze(incident) {
@@ -114,6 +212,39 @@ func (c *Controller) handlePodUpdate(oldObj, newObj interface{}) {
 	}
 }
 
+// eventTime is when an event was last observed
+func eventTime(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil && !event.Series.LastObservedTime.IsZero():
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.CreationTimestamp.Time
+	}
+}
+
+func (c *Controller) handleAnalyzerPodAdd(obj interface{}) {
+	if pod, ok := obj.(*corev1.Pod); ok {
+		c.budget.RecordPod(context.Background(), pod)
+	}
+}
+
+func (c *Controller) handleAnalyzerPodUpdate(oldObj, newObj interface{}) {
+	c.handleAnalyzerPodAdd(newObj)
+}
+
+func (c *Controller) handleAnalyzerPodDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if pod, ok := obj.(*corev1.Pod); ok {
+		c.budget.Forget(pod.UID)
+	}
+}
+
 func (c *Controller) spawnAnalysisJob(ctx context.Context, incident *events.PodIncident) error {
 	jobName := fmt.Sprintf("analyze-%s-%d", incident.PodName, time.Now().Unix())
 
@@ -156,7 +287,7 @@ func (c *Controller) spawnAnalysisJob(ctx context.Context, incident *events.PodI
 								{Name: "CONTAINER_NAME", Value: incident.ContainerName},
 								{Name: "REASON", Value: incident.Reason},
 								{Name: "MESSAGE", Value: incident.Message},
-								{Name: "LLM_PROVIDER", Value: c.config.LLM.Provider},
+								{Name: "NAMESPACE", Value: c.namespace},
 								{Name: "LLM_API_KEY", Value: c.llmAPIKey},
 								{Name: "SLACK_WEBHOOK_URL", Value: c.slackWebhook},
 								{Name: "SLACK_ENABLED", Value: fmt.Sprintf("%t", c.config.Slack.Enabled)},
@@ -185,6 +316,101 @@ func (c *Controller) spawnAnalysisJob(ctx context.Context, incident *events.PodI
 		},
 	}
 
+	// Route the incident to the provider/model configured for its kind
+	route := resolveRoute(c.config.LLM.Routes, incident)
+	if route != nil {
+		klog.Infof("Routing %s for pod %s/%s via %s", incident.EventType, incident.Namespace, incident.PodName, route.Name)
+	}
+	container := &job.Spec.Template.Spec.Containers[0]
+	container.Env = append(container.Env, routeEnv(&c.config.LLM, route)...)
+
+	// Per-provider API keys for the fallback chain, read from their own secrets
+	for i, provider := range c.config.LLM.Providers {
+		if provider.APIKeySecret.Name == "" {
+			continue
+		}
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name: llm.APIKeyEnvVar(i),
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: provider.APIKeySecret.Name},
+					Key:                  provider.APIKeySecret.Key,
+				},
+			},
+		})
+	}
+
+	// Init and ephemeral containers
+	if incident.ContainerKind != "" {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "CONTAINER_KIND", Value: string(incident.ContainerKind)})
+	}
+
+	// Incidents about objects other than pods
+	if incident.Kind != "" {
+		container.Env = append(container.Env, corev1.EnvVar{Name: "OBJECT_KIND", Value: incident.Kind})
+	}
+
+	// Probe details for health check failures
+	if probe := incident.Probe; probe != nil {
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: "PROBE_TYPE", Value: probe.Type},
+			corev1.EnvVar{Name: "PROBE_CONFIG", Value: probe.Config},
+			corev1.EnvVar{Name: "PROBE_RESTARTED", Value: fmt.Sprintf("%t", probe.Restarted)},
+		)
+	}
+
+	// Over budget: the analyzer still notifies, but without calling the LLM
+	if reason := c.budget.Exceeded(); reason != "" {
+		klog.Infof("Skipping LLM analysis for pod %s/%s: %s", incident.Namespace, incident.PodName, reason)
+		container.Env = append(container.Env, corev1.EnvVar{Name: "LLM_SKIP_REASON", Value: reason})
+	}
+
+	// Share the controller configuration with the analyzer
+	if c.config.Analyzer.ConfigMap != "" {
+		podSpec := &job.Spec.Template.Spec
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: c.config.Analyzer.ConfigMap},
+				},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "config",
+			MountPath: analyzerConfigDir,
+			ReadOnly:  true,
+		})
+		container.Env = append(container.Env, corev1.EnvVar{Name: "CONFIG_PATH", Value: analyzerConfigDir + "/config.yaml"})
+	}
+
+	// Runbooks synced to a volume, e.g. by git-sync
+	if runbooks := c.config.Runbooks; runbooks.Enabled && runbooks.PersistentVolumeClaim != "" && runbooks.Dir != "" {
+		podSpec := &job.Spec.Template.Spec
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "runbooks",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: runbooks.PersistentVolumeClaim,
+					ReadOnly:  true,
+				},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "runbooks",
+			MountPath: runbooks.Dir,
+			ReadOnly:  true,
+		})
+	}
+
+	// The root filesystem is read-only, so file backends live on a PVC
+	if cacheCfg := c.config.Cache; cacheCfg.Enabled && cacheCfg.Backend == "file" {
+		mountDataVolume(&job.Spec.Template.Spec, container, "cache", cacheCfg.PersistentVolumeClaim, cacheCfg.Path)
+	}
+	if kb := c.config.Knowledge; kb.Enabled && kb.Backend == "file" {
+		mountDataVolume(&job.Spec.Template.Spec, container, "knowledge", kb.PersistentVolumeClaim, kb.Path)
+	}
+
 	_, err := c.clientset.BatchV1().Jobs(c.namespace).Create(ctx, job, metav1.CreateOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to create job: %w", err)
@@ -194,6 +420,33 @@ func (c *Controller) spawnAnalysisJob(ctx context.Context, incident *events.PodI
 	return nil
 }
 
+// mountDataVolume mounts claim read-write at the directory of path, where a
+// file backend keeps its state. Backends sharing a directory share the mount.
+func mountDataVolume(podSpec *corev1.PodSpec, container *corev1.Container, name, claim, path string) {
+	if claim == "" || path == "" {
+		return
+	}
+	dir := filepath.Dir(path)
+	for _, m := range container.VolumeMounts {
+		if m.MountPath == dir {
+			return
+		}
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claim,
+			},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      name,
+		MountPath: dir,
+	})
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
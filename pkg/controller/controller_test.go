@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMountDataVolume(t *testing.T) {
+	type mount struct{ name, claim, path string }
+	tests := []struct {
+		name       string
+		mounts     []mount
+		wantMounts map[string]string
+	}{
+		{
+			name:       "mounted at the directory of the file",
+			mounts:     []mount{{"cache", "sre-agent-data", "/var/lib/sre-agent/cache.json"}},
+			wantMounts: map[string]string{"cache": "/var/lib/sre-agent"},
+		},
+		{
+			name:       "without a claim nothing is mounted",
+			mounts:     []mount{{"cache", "", "/var/lib/sre-agent/cache.json"}},
+			wantMounts: map[string]string{},
+		},
+		{
+			name: "backends in one directory share the mount",
+			mounts: []mount{
+				{"cache", "sre-agent-data", "/var/lib/sre-agent/cache.json"},
+				{"knowledge", "sre-agent-data", "/var/lib/sre-agent/knowledge.json"},
+			},
+			wantMounts: map[string]string{"cache": "/var/lib/sre-agent"},
+		},
+		{
+			name: "backends in different directories get their own mount",
+			mounts: []mount{
+				{"cache", "cache-data", "/var/lib/cache/cache.json"},
+				{"knowledge", "knowledge-data", "/var/lib/knowledge/knowledge.json"},
+			},
+			wantMounts: map[string]string{"cache": "/var/lib/cache", "knowledge": "/var/lib/knowledge"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			podSpec := &corev1.PodSpec{}
+			container := &corev1.Container{}
+			for _, m := range tt.mounts {
+				mountDataVolume(podSpec, container, m.name, m.claim, m.path)
+			}
+
+			if len(podSpec.Volumes) != len(tt.wantMounts) || len(container.VolumeMounts) != len(tt.wantMounts) {
+				t.Fatalf("got %d volumes and %d mounts, want %d", len(podSpec.Volumes), len(container.VolumeMounts), len(tt.wantMounts))
+			}
+			for _, m := range container.VolumeMounts {
+				if want := tt.wantMounts[m.Name]; m.MountPath != want || m.ReadOnly {
+					t.Errorf("volume %s mounted at %s (read-only %v), want %s read-write", m.Name, m.MountPath, m.ReadOnly, want)
+				}
+			}
+			for _, v := range podSpec.Volumes {
+				if v.PersistentVolumeClaim == nil || v.PersistentVolumeClaim.ReadOnly {
+					t.Errorf("volume %s is not a writable PVC", v.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// resolveRoute returns the first route matching the incident, or nil. Unnamed
+// routes are named after their position.
+func resolveRoute(routes []config.RouteConfig, incident *events.PodIncident) *config.RouteConfig {
+	for i := range routes {
+		if routeMatches(&routes[i], incident) {
+			route := routes[i]
+			if route.Name == "" {
+				route.Name = fmt.Sprintf("route-%d", i)
+			}
+			return &route
+		}
+	}
+	return nil
+}
+
+func routeMatches(route *config.RouteConfig, incident *events.PodIncident) bool {
+	if len(route.EventTypes) > 0 && !contains(route.EventTypes, string(incident.EventType)) {
+		return false
+	}
+	if len(route.Namespaces) > 0 && !contains(route.Namespaces, incident.Namespace) {
+		return false
+	}
+	for key, value := range route.Labels {
+		if incident.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// routeEnv passes the selected route to the analyzer. LLM_PROVIDER is always
+// set; the route variables only when a route matched.
+func routeEnv(cfg *config.LLMConfig, route *config.RouteConfig) []corev1.EnvVar {
+	if route == nil {
+		return []corev1.EnvVar{{Name: "LLM_PROVIDER", Value: cfg.Provider}}
+	}
+
+	provider := route.Provider
+	if provider == "" {
+		provider = cfg.Provider
+	}
+	env := []corev1.EnvVar{
+		{Name: "LLM_PROVIDER", Value: provider},
+		{Name: "LLM_ROUTE", Value: route.Name},
+	}
+	if route.Model != "" {
+		env = append(env, corev1.EnvVar{Name: "LLM_MODEL", Value: route.Model})
+	}
+	if route.MaxTokens > 0 {
+		env = append(env, corev1.EnvVar{Name: "LLM_MAX_TOKENS", Value: strconv.Itoa(route.MaxTokens)})
+	}
+	return env
+}
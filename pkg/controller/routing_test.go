@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/events"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestResolveRoute(t *testing.T) {
+	routes := []config.RouteConfig{
+		{Name: "oom-to-claude", EventTypes: []string{"OOMKilled"}, Provider: "claude"},
+		{Namespaces: []string{"payments"}, Labels: map[string]string{"tier": "critical"}, Model: "gpt-4o"},
+		{Name: "payments", Namespaces: []string{"payments"}, Model: "gpt-4o-mini"},
+	}
+
+	tests := []struct {
+		name     string
+		incident events.PodIncident
+		want     string
+	}{
+		{"event type", events.PodIncident{EventType: events.OOMKilled, Namespace: "payments"}, "oom-to-claude"},
+		{"namespace and labels", events.PodIncident{EventType: events.CrashLoopBackOff, Namespace: "payments", Labels: map[string]string{"tier": "critical", "app": "api"}}, "route-1"},
+		{"label mismatch falls through", events.PodIncident{EventType: events.CrashLoopBackOff, Namespace: "payments", Labels: map[string]string{"tier": "batch"}}, "payments"},
+		{"no match", events.PodIncident{EventType: events.CrashLoopBackOff, Namespace: "shop"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := resolveRoute(routes, &tt.incident)
+			got := ""
+			if route != nil {
+				got = route.Name
+			}
+			if got != tt.want {
+				t.Errorf("resolveRoute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if routes[1].Name != "" {
+		t.Error("resolveRoute renamed the configured route")
+	}
+}
+
+func TestRouteEnv(t *testing.T) {
+	cfg := &config.LLMConfig{Provider: "gemini"}
+
+	tests := []struct {
+		name  string
+		route *config.RouteConfig
+		want  []corev1.EnvVar
+	}{
+		{"no route", nil, []corev1.EnvVar{{Name: "LLM_PROVIDER", Value: "gemini"}}},
+		{"model only", &config.RouteConfig{Name: "cheap", Model: "gemini-flash"}, []corev1.EnvVar{
+			{Name: "LLM_PROVIDER", Value: "gemini"},
+			{Name: "LLM_ROUTE", Value: "cheap"},
+			{Name: "LLM_MODEL", Value: "gemini-flash"},
+		}},
+		{"provider and max tokens", &config.RouteConfig{Name: "oom", Provider: "claude", MaxTokens: 4096}, []corev1.EnvVar{
+			{Name: "LLM_PROVIDER", Value: "claude"},
+			{Name: "LLM_ROUTE", Value: "oom"},
+			{Name: "LLM_MAX_TOKENS", Value: "4096"},
+		}},
+	}
+
+	for _, tt := range tests {
+		if got := routeEnv(cfg, tt.route); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: routeEnv() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
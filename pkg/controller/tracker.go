@@ -43,8 +43,11 @@ func NewIncidentTracker(cooldown time.Duration, escalationEnabled bool, escalati
 
 // ShouldAnalyze checks if incident should be analyzed (not seen recently)
 func (t *IncidentTracker) ShouldAnalyze(incident *events.PodIncident) bool {
-	// Create unique key: namespace/podname/eventtype
+	// Create unique key: namespace/podname/eventtype, with the kind for objects other than pods
 	key := incident.Namespace + "/" + incident.PodName + "/" + string(incident.EventType)
+	if incident.Kind != "" {
+		key = incident.Kind + ":" + key
+	}
 
 	now := time.Now()
 
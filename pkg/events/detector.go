@@ -1,8 +1,13 @@
 package events
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
 )
 
 // EventType represents the type of Kubernetes event
@@ -13,6 +18,22 @@ const (
 	ImagePullBackOff  EventType = "ImagePullBackOff"
 	HealthCheckFailure EventType = "HealthCheckFailure"
 	OOMKilled         EventType = "OOMKilled"
+
+	// Detected from Kubernetes Events, see config.EventsConfig.EventReasons
+	FailedScheduling       EventType = "FailedScheduling"
+	FailedMount            EventType = "FailedMount"
+	FailedAttachVolume     EventType = "FailedAttachVolume"
+	FailedCreatePodSandBox EventType = "FailedCreatePodSandBox"
+	NetworkNotReady        EventType = "NetworkNotReady"
+)
+
+// ContainerKind tells regular, init and ephemeral containers apart
+type ContainerKind string
+
+const (
+	Container          ContainerKind = "container"
+	InitContainer      ContainerKind = "init"
+	EphemeralContainer ContainerKind = "ephemeral"
 )
 
 // PodIncident represents a pod incident that needs analysis
@@ -23,18 +44,82 @@ type PodIncident struct {
 	Reason       string
 	Message      string
 	ContainerName string
+	// ContainerKind is the kind of the failing container, empty when none was identified
+	ContainerKind ContainerKind
+	// Kind of the affected object when it is not a pod, e.g. Node or
+	// PersistentVolumeClaim; PodName then holds the object's name
+	Kind string
+	// Labels of the pod, used to route the incident to a provider/model
+	Labels map[string]string
+	// Probe describes the failing probe of a HealthCheckFailure
+	Probe *ProbeFailure
+}
+
+// ProbeFailure describes a failing liveness, readiness or startup probe
+type ProbeFailure struct {
+	// Type is liveness, readiness or startup
+	Type string
+	// Message is the kubelet's failure message
+	Message string
+	// Config is the probe handler and timings from the pod spec
+	Config string
+	// Restarted is set when the kubelet restarted the container for failing the probe
+	Restarted bool
+}
+
+// defaultFailureThreshold is the kubelet's default probe failureThreshold
+const defaultFailureThreshold = 3
+
+var (
+	// probeFailedRe matches Unhealthy messages: "Liveness probe failed: ..."
+	probeFailedRe = regexp.MustCompile(`^(Liveness|Readiness|Startup) probe (?:failed|errored)`)
+	// probeKilledRe matches Killing messages: "Container app failed liveness probe, will be restarted"
+	probeKilledRe = regexp.MustCompile(`failed (liveness|startup) probe`)
+	// fieldPathContainerRe extracts the container from an involvedObject field path
+	fieldPathContainerRe = regexp.MustCompile(`^spec\.(initContainers|containers|ephemeralContainers)\{(.+)\}$`)
+)
+
+// eventRule is a compiled config.EventReasonConfig
+type eventRule struct {
+	reason    string
+	message   *regexp.Regexp
+	eventType EventType
+	minCount  int32
 }
 
 // Detector detects and filters pod incidents
 type Detector struct {
 	config *config.EventsConfig
+	rules  []eventRule
 }
 
 // NewDetector creates a new event detector
 func NewDetector(cfg *config.EventsConfig) *Detector {
-	return &Detector{
+	d := &Detector{
 		config: cfg,
 	}
+	for _, r := range cfg.EventReasons {
+		if r.Reason == "" || r.EventType == "" {
+			klog.Warningf("Skipping event reason mapping without reason or eventType: %+v", r)
+			continue
+		}
+		rule := eventRule{reason: r.Reason, eventType: EventType(r.EventType), minCount: r.MinCount}
+		if r.MessagePattern != "" {
+			re, err := regexp.Compile(r.MessagePattern)
+			if err != nil {
+				klog.Warningf("Skipping event reason %s: invalid messagePattern: %v", r.Reason, err)
+				continue
+			}
+			rule.message = re
+		}
+		d.rules = append(d.rules, rule)
+	}
+	return d
+}
+
+// WatchesEvents reports whether any incident type is detected from Kubernetes Events
+func (d *Detector) WatchesEvents() bool {
+	return d.config.HealthCheckFailure || len(d.rules) > 0
 }
 
 // ShouldProcess determines if an event should be processed
@@ -49,6 +134,11 @@ func (d *Detector) ShouldProcess(eventType EventType) bool {
 	case OOMKilled:
 		return d.config.OOMKilled
 	default:
+		for _, r := range d.rules {
+			if r.eventType == eventType {
+				return true
+			}
+		}
 		return false
 	}
 }
@@ -57,28 +147,38 @@ func (d *Detector) ShouldProcess(eventType EventType) bool {
 func (d *Detector) DetectIncident(pod *corev1.Pod) *PodIncident {
 	// Check pod status
 	if pod.Status.Phase == corev1.PodFailed {
-		return &PodIncident{
+		incident := &PodIncident{
 			PodName:   pod.Name,
 			Namespace: pod.Namespace,
 			EventType: CrashLoopBackOff,
 			Reason:    pod.Status.Reason,
 			Message:   pod.Status.Message,
 		}
+		// Point the analyzer at the container that failed, so it reads the right logs
+		if name, kind := failedContainer(pod); name != "" {
+			incident.ContainerName, incident.ContainerKind = name, kind
+		}
+		return incident
 	}
 
-	// Check container statuses
-	for _, containerStatus := range pod.Status.ContainerStatuses {
-		if waiting := containerStatus.State.Waiting; waiting != nil {
-			incident := d.detectFromWaiting(pod, containerStatus.Name, waiting)
-			if incident != nil {
-				return incident
+	// Check container statuses; init containers first, since the regular
+	// containers wait in PodInitializing until they succeed
+	for _, statuses := range containerStatuses(pod) {
+		for _, containerStatus := range statuses.list {
+			if waiting := containerStatus.State.Waiting; waiting != nil {
+				incident := d.detectFromWaiting(pod, containerStatus.Name, waiting)
+				if incident != nil {
+					incident.ContainerKind = statuses.kind
+					return incident
+				}
 			}
-		}
 
-		if terminated := containerStatus.State.Terminated; terminated != nil {
-			incident := d.detectFromTerminated(pod, containerStatus.Name, terminated)
-			if incident != nil {
-				return incident
+			if terminated := containerStatus.State.Terminated; terminated != nil {
+				incident := d.detectFromTerminated(pod, containerStatus.Name, terminated)
+				if incident != nil {
+					incident.ContainerKind = statuses.kind
+					return incident
+				}
 			}
 		}
 	}
@@ -86,6 +186,34 @@ func (d *Detector) DetectIncident(pod *corev1.Pod) *PodIncident {
 	return nil
 }
 
+type kindStatuses struct {
+	kind ContainerKind
+	list []corev1.ContainerStatus
+}
+
+// containerStatuses returns the pod's container statuses by kind, in the
+// order the containers run
+func containerStatuses(pod *corev1.Pod) []kindStatuses {
+	return []kindStatuses{
+		{kind: InitContainer, list: pod.Status.InitContainerStatuses},
+		{kind: Container, list: pod.Status.ContainerStatuses},
+		{kind: EphemeralContainer, list: pod.Status.EphemeralContainerStatuses},
+	}
+}
+
+// failedContainer returns the first container of a failed pod that exited
+// with an error
+func failedContainer(pod *corev1.Pod) (string, ContainerKind) {
+	for _, statuses := range containerStatuses(pod) {
+		for _, cs := range statuses.list {
+			if t := cs.State.Terminated; t != nil && t.ExitCode != 0 {
+				return cs.Name, statuses.kind
+			}
+		}
+	}
+	return "", ""
+}
+
 func (d *Detector) detectFromWaiting(pod *corev1.Pod, containerName string, waiting *corev1.ContainerStateWaiting) *PodIncident {
 	switch waiting.Reason {
 	case "CrashLoopBackOff":
@@ -127,3 +255,185 @@ func (d *Detector) detectFromTerminated(pod *corev1.Pod, containerName string, t
 	}
 	return nil
 }
+
+// DetectEvent turns a Kubernetes Event into an incident: probe failures, then
+// the configured event reasons. pod is the involved pod, nil when the event is
+// about another kind of object. Events of a pod whose status already shows a
+// detected failure are left to DetectIncident, which has the container context.
+// Mapped types honour the events.* toggles, e.g. imagePullBackOff: false.
+func (d *Detector) DetectEvent(event *corev1.Event, pod *corev1.Pod) *PodIncident {
+	// The pod status detection comes first: it has the container context, and
+	// a probe restart or image pull back-off shows up in both
+	if pod != nil && d.DetectIncident(pod) != nil {
+		return nil
+	}
+
+	if pod != nil {
+		if incident := d.DetectProbeFailure(event, pod); incident != nil {
+			return incident
+		}
+	}
+
+	rule := d.matchRule(event)
+	if rule == nil || !d.ShouldProcess(rule.eventType) || eventCount(event) < rule.minCount {
+		return nil
+	}
+
+	object := event.InvolvedObject
+	incident := &PodIncident{
+		PodName:   object.Name,
+		Namespace: object.Namespace,
+		EventType: rule.eventType,
+		Reason:    event.Reason,
+		Message:   event.Message,
+	}
+	if pod == nil {
+		incident.Kind = object.Kind
+		if incident.Namespace == "" {
+			// Events of cluster-scoped objects such as Nodes live in the default namespace
+			incident.Namespace = event.Namespace
+		}
+		return incident
+	}
+	incident.ContainerName, incident.ContainerKind = fieldPathContainer(object.FieldPath)
+	return incident
+}
+
+// fieldPathContainer returns the container an event's field path points at,
+// e.g. spec.initContainers{migrate}
+func fieldPathContainer(fieldPath string) (string, ContainerKind) {
+	m := fieldPathContainerRe.FindStringSubmatch(fieldPath)
+	if m == nil {
+		return "", ""
+	}
+	switch m[1] {
+	case "initContainers":
+		return m[2], InitContainer
+	case "ephemeralContainers":
+		return m[2], EphemeralContainer
+	default:
+		return m[2], Container
+	}
+}
+
+func (d *Detector) matchRule(event *corev1.Event) *eventRule {
+	for i, r := range d.rules {
+		if r.reason != event.Reason {
+			continue
+		}
+		if r.message != nil && !r.message.MatchString(event.Message) {
+			continue
+		}
+		return &d.rules[i]
+	}
+	return nil
+}
+
+// DetectProbeFailure turns an Unhealthy event, or a restart the kubelet made
+// because a liveness or startup probe failed, into a HealthCheckFailure. Single
+// failed checks are ignored until the probe's failureThreshold is reached.
+func (d *Detector) DetectProbeFailure(event *corev1.Event, pod *corev1.Pod) *PodIncident {
+	if !d.ShouldProcess(HealthCheckFailure) || event.InvolvedObject.Kind != "Pod" {
+		return nil
+	}
+
+	var probeType string
+	restarted := false
+	switch event.Reason {
+	case "Unhealthy":
+		m := probeFailedRe.FindStringSubmatch(event.Message)
+		if m == nil {
+			return nil
+		}
+		probeType = strings.ToLower(m[1])
+	case "Killing":
+		m := probeKilledRe.FindStringSubmatch(event.Message)
+		if m == nil {
+			return nil
+		}
+		probeType, restarted = m[1], true
+	default:
+		return nil
+	}
+
+	containerName, containerKind := fieldPathContainer(event.InvolvedObject.FieldPath)
+	probe := containerProbe(pod, containerName, probeType)
+
+	threshold := int32(defaultFailureThreshold)
+	if probe != nil && probe.FailureThreshold > 0 {
+		threshold = probe.FailureThreshold
+	}
+	if !restarted && eventCount(event) < threshold {
+		return nil
+	}
+
+	return &PodIncident{
+		PodName:       pod.Name,
+		Namespace:     pod.Namespace,
+		EventType:     HealthCheckFailure,
+		Reason:        event.Reason,
+		Message:       event.Message,
+		ContainerName: containerName,
+		ContainerKind: containerKind,
+		Probe: &ProbeFailure{
+			Type:      probeType,
+			Message:   event.Message,
+			Config:    DescribeProbe(probe),
+			Restarted: restarted,
+		},
+	}
+}
+
+// containerProbe returns the probe of the given type from the pod spec
+func containerProbe(pod *corev1.Pod, containerName, probeType string) *corev1.Probe {
+	// Init containers with restartPolicy Always (sidecars) have probes too
+	for _, c := range append(append([]corev1.Container{}, pod.Spec.Containers...), pod.Spec.InitContainers...) {
+		if containerName != "" && c.Name != containerName {
+			continue
+		}
+		switch probeType {
+		case "liveness":
+			return c.LivenessProbe
+		case "readiness":
+			return c.ReadinessProbe
+		case "startup":
+			return c.StartupProbe
+		}
+	}
+	return nil
+}
+
+// eventCount is how often the kubelet reported an event, from either the
+// legacy count or the event series
+func eventCount(event *corev1.Event) int32 {
+	count := event.Count
+	if event.Series != nil && event.Series.Count > count {
+		count = event.Series.Count
+	}
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
+
+// DescribeProbe summarizes a probe's handler and timings
+func DescribeProbe(p *corev1.Probe) string {
+	if p == nil {
+		return ""
+	}
+
+	var handler string
+	switch {
+	case p.HTTPGet != nil:
+		handler = fmt.Sprintf("httpGet %s on port %s (%s)", p.HTTPGet.Path, p.HTTPGet.Port.String(), p.HTTPGet.Scheme)
+	case p.TCPSocket != nil:
+		handler = fmt.Sprintf("tcpSocket on port %s", p.TCPSocket.Port.String())
+	case p.GRPC != nil:
+		handler = fmt.Sprintf("grpc on port %d", p.GRPC.Port)
+	case p.Exec != nil:
+		handler = "exec " + strings.Join(p.Exec.Command, " ")
+	}
+
+	return fmt.Sprintf("%s initialDelaySeconds=%d periodSeconds=%d timeoutSeconds=%d failureThreshold=%d successThreshold=%d",
+		handler, p.InitialDelaySeconds, p.PeriodSeconds, p.TimeoutSeconds, p.FailureThreshold, p.SuccessThreshold)
+}
@@ -0,0 +1,516 @@
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// testPod is a running pod with an app container probed by an HTTP liveness check
+func testPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-7d9f", Namespace: "payments"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "app",
+				LivenessProbe: &corev1.Probe{
+					ProbeHandler:     corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz", Port: intstr.FromInt(8080), Scheme: corev1.URISchemeHTTP}},
+					PeriodSeconds:    10,
+					TimeoutSeconds:   1,
+					FailureThreshold: 5,
+				},
+			}},
+		},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}},
+		},
+	}
+}
+
+// podEvent is an event about pod, pointing at the app container
+func podEvent(pod *corev1.Pod, reason, message string, count int32) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace},
+		InvolvedObject: corev1.ObjectReference{
+			Kind: "Pod", Name: pod.Name, Namespace: pod.Namespace, FieldPath: "spec.containers{app}",
+		},
+		Reason:  reason,
+		Message: message,
+		Count:   count,
+	}
+}
+
+func TestDetectProbeFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		disabled bool
+		reason   string
+		message  string
+		count    int32
+		series   int32
+		// probeType is empty when no incident is expected
+		probeType string
+		restarted bool
+	}{
+		{
+			name:    "below the failure threshold",
+			reason:  "Unhealthy",
+			message: "Liveness probe failed: HTTP probe failed with statuscode: 500",
+			count:   4,
+		},
+		{
+			name:      "failure threshold reached",
+			reason:    "Unhealthy",
+			message:   "Liveness probe failed: HTTP probe failed with statuscode: 500",
+			count:     5,
+			probeType: "liveness",
+		},
+		{
+			name:      "count from the event series",
+			reason:    "Unhealthy",
+			message:   "Liveness probe failed: Get \"http://10.0.0.5:8080/healthz\": context deadline exceeded",
+			count:     1,
+			series:    6,
+			probeType: "liveness",
+		},
+		{
+			name:      "probe without a spec uses the default threshold",
+			reason:    "Unhealthy",
+			message:   "Readiness probe failed: connection refused",
+			count:     defaultFailureThreshold,
+			probeType: "readiness",
+		},
+		{
+			name:      "restart by the kubelet",
+			reason:    "Killing",
+			message:   "Container app failed liveness probe, will be restarted",
+			count:     1,
+			probeType: "liveness",
+			restarted: true,
+		},
+		{
+			name:    "killing for another reason",
+			reason:  "Killing",
+			message: "Stopping container app",
+			count:   10,
+		},
+		{
+			name:    "unrelated event",
+			reason:  "Pulled",
+			message: "Container image already present on machine",
+			count:   10,
+		},
+		{
+			name:     "health check failures disabled",
+			disabled: true,
+			reason:   "Killing",
+			message:  "Container app failed liveness probe, will be restarted",
+			count:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDetector(&config.EventsConfig{HealthCheckFailure: !tt.disabled})
+			pod := testPod()
+			event := podEvent(pod, tt.reason, tt.message, tt.count)
+			if tt.series > 0 {
+				event.Series = &corev1.EventSeries{Count: tt.series}
+			}
+
+			incident := d.DetectProbeFailure(event, pod)
+			if tt.probeType == "" {
+				if incident != nil {
+					t.Errorf("DetectProbeFailure() = %+v, want nil", incident)
+				}
+				return
+			}
+			if incident == nil {
+				t.Fatal("DetectProbeFailure() = nil, want an incident")
+			}
+			if incident.EventType != HealthCheckFailure || incident.ContainerName != "app" || incident.ContainerKind != Container {
+				t.Errorf("incident = %+v, want a HealthCheckFailure of container app", incident)
+			}
+			if incident.Probe.Type != tt.probeType || incident.Probe.Restarted != tt.restarted || incident.Probe.Message != tt.message {
+				t.Errorf("Probe = %+v, want %s probe, restarted %v", incident.Probe, tt.probeType, tt.restarted)
+			}
+			if tt.probeType == "liveness" && !strings.Contains(incident.Probe.Config, "httpGet /healthz on port 8080") {
+				t.Errorf("Probe.Config = %q, want the liveness probe of the spec", incident.Probe.Config)
+			}
+		})
+	}
+}
+
+func TestDetectProbeFailureIgnoresOtherObjects(t *testing.T) {
+	d := NewDetector(&config.EventsConfig{HealthCheckFailure: true})
+	pod := testPod()
+	event := podEvent(pod, "Killing", "Container app failed liveness probe, will be restarted", 1)
+	event.InvolvedObject.Kind = "Node"
+
+	if incident := d.DetectProbeFailure(event, pod); incident != nil {
+		t.Errorf("DetectProbeFailure() = %+v for a Node event, want nil", incident)
+	}
+}
+
+func TestDescribeProbe(t *testing.T) {
+	const zeroTimings = "initialDelaySeconds=0 periodSeconds=0 timeoutSeconds=0 failureThreshold=0 successThreshold=0"
+	tests := []struct {
+		name  string
+		probe *corev1.Probe
+		want  string
+	}{
+		{"no probe", nil, ""},
+		{"http", testPod().Spec.Containers[0].LivenessProbe, "httpGet /healthz on port 8080 (HTTP) initialDelaySeconds=0 periodSeconds=10 timeoutSeconds=1 failureThreshold=5 successThreshold=0"},
+		{"tcp", &corev1.Probe{ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")}}}, "tcpSocket on port http " + zeroTimings},
+		{"exec", &corev1.Probe{ProbeHandler: corev1.ProbeHandler{Exec: &corev1.ExecAction{Command: []string{"cat", "/tmp/ready"}}}}, "exec cat /tmp/ready " + zeroTimings},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeProbe(tt.probe); got != tt.want {
+				t.Errorf("DescribeProbe() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldProcessHealthCheckFailure(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		d := NewDetector(&config.EventsConfig{HealthCheckFailure: enabled})
+		if got := d.ShouldProcess(HealthCheckFailure); got != enabled {
+			t.Errorf("ShouldProcess(HealthCheckFailure) = %v with healthCheckFailure %v", got, enabled)
+		}
+		if got := d.WatchesEvents(); got != enabled {
+			t.Errorf("WatchesEvents() = %v with healthCheckFailure %v and no event reasons", got, enabled)
+		}
+	}
+}
+
+// eventRules maps the reasons used by the rule tests, including a BackOff
+// message pattern that tells image pull back-offs from restart back-offs
+var eventRules = []config.EventReasonConfig{
+	{Reason: "FailedScheduling", EventType: string(FailedScheduling), MinCount: 3},
+	{Reason: "FailedMount", EventType: string(FailedMount)},
+	{Reason: "BackOff", MessagePattern: "^Back-off pulling image", EventType: string(ImagePullBackOff)},
+	{Reason: "NodeNotReady", EventType: "NodeNotReady"},
+	{Reason: "Invalid", MessagePattern: "(", EventType: "Invalid"},
+	{Reason: "", EventType: "Empty"},
+}
+
+func TestNewDetectorRules(t *testing.T) {
+	d := NewDetector(&config.EventsConfig{EventReasons: eventRules})
+	if len(d.rules) != 4 {
+		t.Errorf("compiled %d rules, want the 4 valid ones", len(d.rules))
+	}
+	if !d.WatchesEvents() {
+		t.Error("WatchesEvents() = false with event reasons configured")
+	}
+	for eventType, want := range map[EventType]bool{FailedMount: true, "NodeNotReady": true, "Invalid": false, NetworkNotReady: false} {
+		if got := d.ShouldProcess(eventType); got != want {
+			t.Errorf("ShouldProcess(%s) = %v, want %v", eventType, got, want)
+		}
+	}
+}
+
+func TestDetectEvent(t *testing.T) {
+	tests := []struct {
+		name             string
+		imagePullBackOff bool
+		reason           string
+		message          string
+		count            int32
+		want             EventType
+	}{
+		{
+			name:    "mapped reason",
+			reason:  "FailedMount",
+			message: "MountVolume.SetUp failed for volume \"config\" : configmap \"settings\" not found",
+			count:   1,
+			want:    FailedMount,
+		},
+		{
+			name:    "below the minimum count",
+			reason:  "FailedScheduling",
+			message: "0/3 nodes are available: 3 Insufficient cpu.",
+			count:   2,
+		},
+		{
+			name:    "minimum count reached",
+			reason:  "FailedScheduling",
+			message: "0/3 nodes are available: 3 Insufficient cpu.",
+			count:   3,
+			want:    FailedScheduling,
+		},
+		{
+			name:             "message pattern matches",
+			imagePullBackOff: true,
+			reason:           "BackOff",
+			message:          "Back-off pulling image \"registry.example.com/api:v2\"",
+			count:            1,
+			want:             ImagePullBackOff,
+		},
+		{
+			name:             "message pattern does not match",
+			imagePullBackOff: true,
+			reason:           "BackOff",
+			message:          "Back-off restarting failed container app in pod api-7d9f",
+			count:            1,
+		},
+		{
+			name:    "mapped type disabled by its toggle",
+			reason:  "BackOff",
+			message: "Back-off pulling image \"registry.example.com/api:v2\"",
+			count:   1,
+		},
+		{
+			name:    "unmapped reason",
+			reason:  "Scheduled",
+			message: "Successfully assigned payments/api-7d9f to node-1",
+			count:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDetector(&config.EventsConfig{ImagePullBackOff: tt.imagePullBackOff, EventReasons: eventRules})
+			pod := testPod()
+			pod.Status.Phase = corev1.PodPending
+
+			incident := d.DetectEvent(podEvent(pod, tt.reason, tt.message, tt.count), pod)
+			if tt.want == "" {
+				if incident != nil {
+					t.Errorf("DetectEvent() = %+v, want nil", incident)
+				}
+				return
+			}
+			if incident == nil {
+				t.Fatalf("DetectEvent() = nil, want %s", tt.want)
+			}
+			if incident.EventType != tt.want || incident.Reason != tt.reason || incident.Message != tt.message {
+				t.Errorf("incident = %+v, want %s from the event", incident, tt.want)
+			}
+			if incident.PodName != pod.Name || incident.Namespace != pod.Namespace || incident.Kind != "" {
+				t.Errorf("incident = %+v, want pod %s/%s", incident, pod.Namespace, pod.Name)
+			}
+			if incident.ContainerName != "app" || incident.ContainerKind != Container {
+				t.Errorf("container = %s (%s), want app from the field path", incident.ContainerName, incident.ContainerKind)
+			}
+		})
+	}
+}
+
+func TestDetectEventLeavesPodStatusFailuresToDetectIncident(t *testing.T) {
+	d := NewDetector(&config.EventsConfig{CrashLoopBackOff: true, HealthCheckFailure: true, EventReasons: eventRules})
+	pod := testPod()
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{
+		Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 5m0s restarting failed container"},
+	}
+
+	for _, event := range []*corev1.Event{
+		podEvent(pod, "Killing", "Container app failed liveness probe, will be restarted", 1),
+		podEvent(pod, "FailedMount", "MountVolume.SetUp failed", 1),
+	} {
+		if incident := d.DetectEvent(event, pod); incident != nil {
+			t.Errorf("DetectEvent(%s) = %+v, want nil while the pod status reports the failure", event.Reason, incident)
+		}
+	}
+
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	incident := d.DetectEvent(podEvent(pod, "Killing", "Container app failed liveness probe, will be restarted", 1), pod)
+	if incident == nil || incident.EventType != HealthCheckFailure {
+		t.Errorf("DetectEvent() = %+v, want a HealthCheckFailure once the pod is running", incident)
+	}
+}
+
+func TestDetectEventClusterScopedObject(t *testing.T) {
+	d := NewDetector(&config.EventsConfig{EventReasons: eventRules})
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "node-1"},
+		Reason:         "NodeNotReady",
+		Message:        "Node node-1 status is now: NodeNotReady",
+	}
+
+	incident := d.DetectEvent(event, nil)
+	if incident == nil {
+		t.Fatal("DetectEvent() = nil, want a node incident")
+	}
+	if incident.Kind != "Node" || incident.PodName != "node-1" || incident.Namespace != "default" {
+		t.Errorf("incident = %+v, want Node node-1 in the event's namespace", incident)
+	}
+	if incident.ContainerName != "" {
+		t.Errorf("ContainerName = %q, want none for a node", incident.ContainerName)
+	}
+}
+
+func TestDetectIncident(t *testing.T) {
+	waiting := func(name, reason string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}}
+	}
+	terminated := func(name, reason string, exitCode int32) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: reason, ExitCode: exitCode}}}
+	}
+	running := func(name string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}}
+	}
+
+	tests := []struct {
+		name      string
+		phase     corev1.PodPhase
+		init      []corev1.ContainerStatus
+		regular   []corev1.ContainerStatus
+		ephemeral []corev1.ContainerStatus
+		want      EventType
+		container string
+		kind      ContainerKind
+	}{
+		{
+			name:    "healthy pod",
+			phase:   corev1.PodRunning,
+			init:    []corev1.ContainerStatus{terminated("migrate", "Completed", 0)},
+			regular: []corev1.ContainerStatus{running("app")},
+		},
+		{
+			name:      "crash looping container",
+			phase:     corev1.PodRunning,
+			regular:   []corev1.ContainerStatus{running("proxy"), waiting("app", "CrashLoopBackOff")},
+			want:      CrashLoopBackOff,
+			container: "app",
+			kind:      Container,
+		},
+		{
+			name:      "init container before the waiting app",
+			phase:     corev1.PodPending,
+			init:      []corev1.ContainerStatus{waiting("migrate", "CrashLoopBackOff")},
+			regular:   []corev1.ContainerStatus{waiting("app", "PodInitializing")},
+			want:      CrashLoopBackOff,
+			container: "migrate",
+			kind:      InitContainer,
+		},
+		{
+			name:      "init container image pull",
+			phase:     corev1.PodPending,
+			init:      []corev1.ContainerStatus{waiting("migrate", "ErrImagePull")},
+			want:      ImagePullBackOff,
+			container: "migrate",
+			kind:      InitContainer,
+		},
+		{
+			name:      "ephemeral debug container",
+			phase:     corev1.PodRunning,
+			regular:   []corev1.ContainerStatus{running("app")},
+			ephemeral: []corev1.ContainerStatus{terminated("debugger", "OOMKilled", 137)},
+			want:      OOMKilled,
+			container: "debugger",
+			kind:      EphemeralContainer,
+		},
+		{
+			name:      "failed pod points at the failed init container",
+			phase:     corev1.PodFailed,
+			init:      []corev1.ContainerStatus{terminated("migrate", "Error", 1)},
+			regular:   []corev1.ContainerStatus{waiting("app", "PodInitializing")},
+			want:      CrashLoopBackOff,
+			container: "migrate",
+			kind:      InitContainer,
+		},
+		{
+			name:      "failed pod points at the failed container",
+			phase:     corev1.PodFailed,
+			init:      []corev1.ContainerStatus{terminated("migrate", "Completed", 0)},
+			regular:   []corev1.ContainerStatus{terminated("app", "Error", 2)},
+			want:      CrashLoopBackOff,
+			container: "app",
+			kind:      Container,
+		},
+	}
+
+	d := NewDetector(&config.EventsConfig{CrashLoopBackOff: true, ImagePullBackOff: true, OOMKilled: true})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := testPod()
+			pod.Status = corev1.PodStatus{
+				Phase:                      tt.phase,
+				InitContainerStatuses:      tt.init,
+				ContainerStatuses:          tt.regular,
+				EphemeralContainerStatuses: tt.ephemeral,
+			}
+
+			incident := d.DetectIncident(pod)
+			if tt.want == "" {
+				if incident != nil {
+					t.Errorf("DetectIncident() = %+v, want nil", incident)
+				}
+				return
+			}
+			if incident == nil {
+				t.Fatalf("DetectIncident() = nil, want %s", tt.want)
+			}
+			if incident.EventType != tt.want || incident.ContainerName != tt.container || incident.ContainerKind != tt.kind {
+				t.Errorf("incident = %s in %s (%s), want %s in %s (%s)",
+					incident.EventType, incident.ContainerName, incident.ContainerKind, tt.want, tt.container, tt.kind)
+			}
+		})
+	}
+}
+
+func TestDetectIncidentHonoursToggles(t *testing.T) {
+	pod := testPod()
+	pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+		Name: "migrate", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+	}}
+
+	d := NewDetector(&config.EventsConfig{CrashLoopBackOff: true})
+	if incident := d.DetectIncident(pod); incident != nil {
+		t.Errorf("DetectIncident() = %+v with imagePullBackOff disabled, want nil", incident)
+	}
+}
+
+func TestFieldPathContainer(t *testing.T) {
+	tests := []struct {
+		fieldPath string
+		name      string
+		kind      ContainerKind
+	}{
+		{"spec.containers{app}", "app", Container},
+		{"spec.initContainers{migrate}", "migrate", InitContainer},
+		{"spec.ephemeralContainers{debugger-x7k2}", "debugger-x7k2", EphemeralContainer},
+		{"spec.containers", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		name, kind := fieldPathContainer(tt.fieldPath)
+		if name != tt.name || kind != tt.kind {
+			t.Errorf("fieldPathContainer(%q) = %q, %q, want %q, %q", tt.fieldPath, name, kind, tt.name, tt.kind)
+		}
+	}
+}
+
+func TestDetectProbeFailureOfSidecar(t *testing.T) {
+	always := corev1.ContainerRestartPolicyAlways
+	pod := testPod()
+	pod.Spec.InitContainers = []corev1.Container{{
+		Name:          "proxy",
+		RestartPolicy: &always,
+		StartupProbe:  &corev1.Probe{ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(15021)}}, FailureThreshold: 2},
+	}}
+	event := podEvent(pod, "Unhealthy", "Startup probe failed: dial tcp 10.0.0.5:15021: connect: connection refused", 2)
+	event.InvolvedObject.FieldPath = "spec.initContainers{proxy}"
+
+	d := NewDetector(&config.EventsConfig{HealthCheckFailure: true})
+	incident := d.DetectProbeFailure(event, pod)
+	if incident == nil {
+		t.Fatal("DetectProbeFailure() = nil, want the sidecar's startup probe failure")
+	}
+	if incident.ContainerName != "proxy" || incident.ContainerKind != InitContainer {
+		t.Errorf("container = %s (%s), want the proxy init container", incident.ContainerName, incident.ContainerKind)
+	}
+	if !strings.HasPrefix(incident.Probe.Config, "tcpSocket on port 15021") {
+		t.Errorf("Probe.Config = %q, want the sidecar's startup probe", incident.Probe.Config)
+	}
+}
@@ -0,0 +1,338 @@
+package guard
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CommandClass is the impact of running a kubectl command
+type CommandClass int
+
+const (
+	CommandReadOnly CommandClass = iota
+	CommandMutating
+	CommandDestructive
+)
+
+func (c CommandClass) String() string {
+	switch c {
+	case CommandMutating:
+		return "mutating"
+	case CommandDestructive:
+		return "destructive"
+	default:
+		return "read-only"
+	}
+}
+
+// readOnlyVerbs never change cluster state
+var readOnlyVerbs = map[string]bool{
+	"get": true, "describe": true, "logs": true, "top": true, "explain": true, "events": true,
+	"api-resources": true, "api-versions": true, "version": true, "cluster-info": true,
+	"diff": true, "wait": true, "port-forward": true, "auth": true, "config": true,
+}
+
+// nodeVerbs and podVerbs take a bare node or pod name instead of a resource type
+var (
+	nodeVerbs = map[string]bool{"drain": true, "cordon": true, "uncordon": true}
+	podVerbs  = map[string]bool{"logs": true, "exec": true, "attach": true, "port-forward": true}
+)
+
+// readOnlyRolloutVerbs are the rollout subcommands that only report
+var readOnlyRolloutVerbs = map[string]bool{"status": true, "history": true}
+
+// Command is a kubectl invocation parsed from an analysis
+type Command struct {
+	Verb      string
+	Namespace string
+	// Kind and Name identify the first resource the command refers to, if any
+	Kind string
+	Name string
+
+	Class CommandClass
+	// Reason explains a destructive classification
+	Reason string
+}
+
+var (
+	// kubectlRe finds kubectl invocations in prose, up to a backtick or the end of the line
+	kubectlRe   = regexp.MustCompile("\\bkubectl\\s[^`\\n]+")
+	separatorRe = regexp.MustCompile(`\|\||&&|[|;]`)
+	// resourceNameRe rejects placeholders and misparsed flag values before a lookup
+	resourceNameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+)
+
+// ParseCommands returns the kubectl commands in text, e.g. a suggested command
+// or a fix step quoting one. Chained and piped commands are parsed separately.
+func ParseCommands(text string) []*Command {
+	var commands []*Command
+	for _, invocation := range kubectlRe.FindAllString(text, -1) {
+		// Drop a trailing shell comment, such as an earlier annotation
+		invocation, _, _ = strings.Cut(invocation, " #")
+		for _, segment := range separatorRe.Split(invocation, -1) {
+			args := strings.Fields(strings.NewReplacer(`"`, "", "'", "").Replace(segment))
+			if len(args) > 1 && args[0] == "kubectl" {
+				commands = append(commands, parseCommand(args[1:]))
+			}
+		}
+	}
+	return commands
+}
+
+func parseCommand(args []string) *Command {
+	cmd := &Command{}
+	var positional []string
+	flags := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			// The rest is the command run by exec or debug
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch name {
+		case "n", "namespace", "l", "selector", "o", "output", "c", "container", "context", "replicas",
+			"grace-period", "f", "filename", "sort-by", "tail", "since", "field-selector", "timeout", "image":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+		}
+		flags[name] = value
+	}
+
+	if len(positional) == 0 {
+		return cmd
+	}
+	cmd.Verb = positional[0]
+	resources := positional[1:]
+	// Subcommands name the operation, not the resource
+	switch cmd.Verb {
+	case "rollout", "set", "auth", "config":
+		if len(resources) > 0 {
+			cmd.Verb += " " + resources[0]
+			resources = resources[1:]
+		}
+	}
+	cmd.Namespace = firstNonEmpty(flags["n"], flags["namespace"])
+
+	if len(resources) > 0 {
+		if kind, name, ok := strings.Cut(resources[0], "/"); ok {
+			cmd.Kind, cmd.Name = kind, name
+		} else if nodeVerbs[cmd.Verb] {
+			cmd.Kind, cmd.Name = "node", resources[0]
+		} else if podVerbs[cmd.Verb] {
+			cmd.Kind, cmd.Name = "pod", resources[0]
+		} else {
+			cmd.Kind = resources[0]
+			if len(resources) > 1 {
+				cmd.Name = resources[1]
+			}
+		}
+	}
+
+	cmd.Class, cmd.Reason = classify(cmd, flags)
+	return cmd
+}
+
+// classify rates a command by its verb, then raises mutating commands that
+// remove workloads or act on everything to destructive
+func classify(cmd *Command, flags map[string]string) (CommandClass, string) {
+	verb, _, _ := strings.Cut(cmd.Verb, " ")
+	_, all := flags["all"]
+	_, allNamespaces := flags["all-namespaces"]
+	_, allNamespacesShort := flags["A"]
+	_, force := flags["force"]
+
+	switch {
+	case verb == "rollout" && readOnlyRolloutVerbs[strings.TrimPrefix(cmd.Verb, "rollout ")]:
+		return CommandReadOnly, ""
+	case verb == "config" && cmd.Verb != "config view" && cmd.Verb != "config get-contexts" && cmd.Verb != "config current-context":
+		return CommandMutating, ""
+	case readOnlyVerbs[verb]:
+		return CommandReadOnly, ""
+	case verb == "drain":
+		return CommandDestructive, "evicts every pod on node " + cmd.Name
+	case verb == "delete" && isKind(cmd.Kind, "namespace"):
+		return CommandDestructive, "deletes namespace " + cmd.Name + " and everything in it"
+	case verb == "delete" && (isKind(cmd.Kind, "persistentvolumeclaim") || isKind(cmd.Kind, "persistentvolume")):
+		return CommandDestructive, "deletes storage and possibly its data"
+	case verb == "delete" && (all || flags["l"] != "" || flags["selector"] != ""):
+		return CommandDestructive, "deletes every matching " + cmd.Kind
+	case verb == "delete" && !isKind(cmd.Kind, "pod"):
+		return CommandDestructive, "deletes " + strings.Trim(cmd.Kind+" "+cmd.Name, " ")
+	case verb == "scale" && flags["replicas"] == "0":
+		return CommandDestructive, "scales " + strings.Trim(cmd.Kind+" "+cmd.Name, " ") + " to zero"
+	case all || allNamespaces || allNamespacesShort:
+		return CommandDestructive, "applies to all resources or namespaces"
+	case force && flags["grace-period"] == "0":
+		return CommandDestructive, "skips graceful termination"
+	}
+	// Deleting a single pod is a routine restart; its controller recreates it
+	return CommandMutating, ""
+}
+
+// kindAliases maps the resource names accepted by kubectl to a canonical kind
+var kindAliases = map[string]string{
+	"po": "pod", "pod": "pod", "pods": "pod",
+	"deploy": "deployment", "deployment": "deployment", "deployments": "deployment",
+	"sts": "statefulset", "statefulset": "statefulset", "statefulsets": "statefulset",
+	"ds": "daemonset", "daemonset": "daemonset", "daemonsets": "daemonset",
+	"rs": "replicaset", "replicaset": "replicaset", "replicasets": "replicaset",
+	"svc": "service", "service": "service", "services": "service",
+	"cm": "configmap", "configmap": "configmap", "configmaps": "configmap",
+	"pvc": "persistentvolumeclaim", "persistentvolumeclaim": "persistentvolumeclaim", "persistentvolumeclaims": "persistentvolumeclaim",
+	"pv": "persistentvolume", "persistentvolume": "persistentvolume", "persistentvolumes": "persistentvolume",
+	"job": "job", "jobs": "job",
+	"cj": "cronjob", "cronjob": "cronjob", "cronjobs": "cronjob",
+	"ns": "namespace", "namespace": "namespace", "namespaces": "namespace",
+	"no": "node", "node": "node", "nodes": "node",
+}
+
+func canonicalKind(kind string) string {
+	kind = strings.ToLower(kind)
+	// Strip a group suffix, e.g. deployments.apps
+	kind, _, _ = strings.Cut(kind, ".")
+	return kindAliases[kind]
+}
+
+func isKind(kind, canonical string) bool {
+	return canonicalKind(kind) == canonical
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// CommandPolicy decides what happens to the commands of an analysis
+type CommandPolicy struct {
+	// StripDestructive removes destructive commands instead of annotating them
+	StripDestructive bool
+	// Clientset verifies that referenced resources exist; nil skips the check
+	Clientset *kubernetes.Clientset
+	// Namespace is assumed for commands without -n
+	Namespace string
+}
+
+// CommandReport counts what the policy found
+type CommandReport struct {
+	Destructive int
+	Mutating    int
+	Missing     int
+	Stripped    int
+}
+
+// ApplyCommandPolicy annotates the suggested commands and fix steps of an
+// analysis with their impact and missing resources, and strips destructive
+// ones when configured. Read-only commands are left alone.
+func ApplyCommandPolicy(ctx context.Context, analysis *llm.Analysis, policy CommandPolicy) CommandReport {
+	var report CommandReport
+	check := func(text string) (string, bool) {
+		var notes []string
+		class := CommandReadOnly
+		for _, cmd := range ParseCommands(text) {
+			if cmd.Class > class {
+				class = cmd.Class
+			}
+			if cmd.Reason != "" {
+				notes = append(notes, cmd.Reason)
+			}
+			if policy.Clientset != nil {
+				if missing := policy.missing(ctx, cmd); missing != "" {
+					report.Missing++
+					notes = append(notes, missing+" not found")
+				}
+			}
+		}
+
+		switch class {
+		case CommandDestructive:
+			report.Destructive++
+			if policy.StripDestructive {
+				report.Stripped++
+				return "", false
+			}
+			return fmt.Sprintf("%s  # DESTRUCTIVE: %s", text, strings.Join(notes, "; ")), true
+		case CommandMutating:
+			report.Mutating++
+			notes = append([]string{"changes the cluster"}, notes...)
+		}
+		if len(notes) == 0 {
+			return text, true
+		}
+		return fmt.Sprintf("%s  # %s", text, strings.Join(notes, "; ")), true
+	}
+
+	analysis.KubectlCommands = filterMap(analysis.KubectlCommands, check)
+	analysis.ImmediateFix = filterMap(analysis.ImmediateFix, check)
+	return report
+}
+
+func filterMap(items []string, fn func(string) (string, bool)) []string {
+	kept := items[:0]
+	for _, item := range items {
+		if out, ok := fn(item); ok {
+			kept = append(kept, out)
+		}
+	}
+	return kept
+}
+
+// missing returns "kind/name" when the command names a resource that does not
+// exist. Lookup errors other than NotFound (e.g. RBAC) leave it unverified.
+func (p CommandPolicy) missing(ctx context.Context, cmd *Command) string {
+	if !resourceNameRe.MatchString(cmd.Name) || cmd.Verb == "create" || cmd.Verb == "apply" {
+		return ""
+	}
+	namespace := firstNonEmpty(cmd.Namespace, p.Namespace)
+	opts := metav1.GetOptions{}
+	var err error
+	switch canonicalKind(cmd.Kind) {
+	case "pod":
+		_, err = p.Clientset.CoreV1().Pods(namespace).Get(ctx, cmd.Name, opts)
+	case "deployment":
+		_, err = p.Clientset.AppsV1().Deployments(namespace).Get(ctx, cmd.Name, opts)
+	case "statefulset":
+		_, err = p.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, cmd.Name, opts)
+	case "daemonset":
+		_, err = p.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, cmd.Name, opts)
+	case "replicaset":
+		_, err = p.Clientset.AppsV1().ReplicaSets(namespace).Get(ctx, cmd.Name, opts)
+	case "service":
+		_, err = p.Clientset.CoreV1().Services(namespace).Get(ctx, cmd.Name, opts)
+	case "configmap":
+		_, err = p.Clientset.CoreV1().ConfigMaps(namespace).Get(ctx, cmd.Name, opts)
+	case "persistentvolumeclaim":
+		_, err = p.Clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, cmd.Name, opts)
+	case "job":
+		_, err = p.Clientset.BatchV1().Jobs(namespace).Get(ctx, cmd.Name, opts)
+	case "cronjob":
+		_, err = p.Clientset.BatchV1().CronJobs(namespace).Get(ctx, cmd.Name, opts)
+	case "namespace":
+		_, err = p.Clientset.CoreV1().Namespaces().Get(ctx, cmd.Name, opts)
+	case "node":
+		_, err = p.Clientset.CoreV1().Nodes().Get(ctx, cmd.Name, opts)
+	default:
+		return ""
+	}
+	if apierrors.IsNotFound(err) {
+		return canonicalKind(cmd.Kind) + "/" + cmd.Name
+	}
+	return ""
+}
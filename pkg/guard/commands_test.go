@@ -0,0 +1,225 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Command
+	}{
+		{
+			name: "kind/name and namespace",
+			text: "kubectl -n payments describe deploy/api",
+			want: []Command{{Verb: "describe", Namespace: "payments", Kind: "deploy", Name: "api", Class: CommandReadOnly}},
+		},
+		{
+			name: "kind and name",
+			text: "Run `kubectl get pod api-7d9f --namespace=payments -o yaml` to inspect it",
+			want: []Command{{Verb: "get", Namespace: "payments", Kind: "pod", Name: "api-7d9f", Class: CommandReadOnly}},
+		},
+		{
+			name: "bare pod name",
+			text: "kubectl logs api-7d9f -c app --previous",
+			want: []Command{{Verb: "logs", Kind: "pod", Name: "api-7d9f", Class: CommandReadOnly}},
+		},
+		{
+			name: "bare node name",
+			text: "kubectl cordon node-1",
+			want: []Command{{Verb: "cordon", Kind: "node", Name: "node-1", Class: CommandMutating}},
+		},
+		{
+			name: "subcommand",
+			text: "kubectl rollout status deployment/api",
+			want: []Command{{Verb: "rollout status", Kind: "deployment", Name: "api", Class: CommandReadOnly}},
+		},
+		{
+			name: "chained and piped",
+			text: "kubectl get pods -n web | grep api && kubectl delete pod api-1 -n web",
+			want: []Command{
+				{Verb: "get", Namespace: "web", Kind: "pods", Class: CommandReadOnly},
+				{Verb: "delete", Namespace: "web", Kind: "pod", Name: "api-1", Class: CommandMutating},
+			},
+		},
+		{
+			name: "exec command after --",
+			text: "kubectl exec -it api-1 -- rm -rf /tmp/cache",
+			want: []Command{{Verb: "exec", Kind: "pod", Name: "api-1", Class: CommandMutating}},
+		},
+		{
+			name: "trailing comment",
+			text: "kubectl get pods # lists pods",
+			want: []Command{{Verb: "get", Kind: "pods", Class: CommandReadOnly}},
+		},
+		{
+			name: "no command",
+			text: "Increase the memory limit of the container",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Command
+			for _, cmd := range ParseCommands(tt.text) {
+				got = append(got, *cmd)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommands(%q) = %+v\nwant %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		command string
+		want    CommandClass
+	}{
+		{"kubectl get pods -A", CommandReadOnly},
+		{"kubectl rollout history deploy/api", CommandReadOnly},
+		{"kubectl config view", CommandReadOnly},
+		{"kubectl config use-context prod", CommandMutating},
+		{"kubectl rollout restart deploy/api", CommandMutating},
+		{"kubectl delete pod api-1", CommandMutating},
+		{"kubectl scale deploy/api --replicas=3", CommandMutating},
+		{"kubectl set image deploy/api app=api:v2", CommandMutating},
+		{"kubectl drain node-1 --ignore-daemonsets", CommandDestructive},
+		{"kubectl delete ns payments", CommandDestructive},
+		{"kubectl delete pvc data-db-0", CommandDestructive},
+		{"kubectl delete pods -l app=api", CommandDestructive},
+		{"kubectl delete deployments.apps api", CommandDestructive},
+		{"kubectl scale deploy/api --replicas 0", CommandDestructive},
+		{"kubectl delete pods --all", CommandDestructive},
+		{"kubectl rollout restart deploy --all-namespaces", CommandDestructive},
+		{"kubectl delete pod api-1 --force --grace-period=0", CommandDestructive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			commands := ParseCommands(tt.command)
+			if len(commands) != 1 {
+				t.Fatalf("parsed %d commands, want 1", len(commands))
+			}
+			cmd := commands[0]
+			if cmd.Class != tt.want {
+				t.Errorf("Class = %v, want %v", cmd.Class, tt.want)
+			}
+			if (cmd.Reason != "") != (tt.want == CommandDestructive) {
+				t.Errorf("Reason = %q, want one only for destructive commands", cmd.Reason)
+			}
+		})
+	}
+}
+
+func TestApplyCommandPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		strip       bool
+		commands    []string
+		fix         []string
+		want        []string
+		wantFix     []string
+		destructive int
+		mutating    int
+	}{
+		{
+			name:        "annotate",
+			commands:    []string{"kubectl get pods", "kubectl rollout restart deploy/api", "kubectl delete ns payments"},
+			fix:         []string{"Raise the memory limit"},
+			want:        []string{"kubectl get pods", "kubectl rollout restart deploy/api  # changes the cluster", "kubectl delete ns payments  # DESTRUCTIVE: deletes namespace payments and everything in it"},
+			wantFix:     []string{"Raise the memory limit"},
+			destructive: 1,
+			mutating:    1,
+		},
+		{
+			name:        "strip",
+			strip:       true,
+			commands:    []string{"kubectl get pods", "kubectl drain node-1"},
+			fix:         []string{"Run kubectl scale deploy/api --replicas=0 then redeploy"},
+			want:        []string{"kubectl get pods"},
+			destructive: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := &llm.Analysis{KubectlCommands: tt.commands, ImmediateFix: tt.fix}
+			report := ApplyCommandPolicy(context.Background(), analysis, CommandPolicy{StripDestructive: tt.strip})
+
+			if !reflect.DeepEqual(analysis.KubectlCommands, tt.want) {
+				t.Errorf("KubectlCommands = %q, want %q", analysis.KubectlCommands, tt.want)
+			}
+			if strings.Join(analysis.ImmediateFix, "\n") != strings.Join(tt.wantFix, "\n") {
+				t.Errorf("ImmediateFix = %q, want %q", analysis.ImmediateFix, tt.wantFix)
+			}
+			if report.Destructive != tt.destructive || report.Mutating != tt.mutating {
+				t.Errorf("report = %+v, want %d destructive, %d mutating", report, tt.destructive, tt.mutating)
+			}
+			if tt.strip && report.Stripped != tt.destructive {
+				t.Errorf("Stripped = %d, want %d", report.Stripped, tt.destructive)
+			}
+		})
+	}
+}
+
+func TestApplyCommandPolicyVerifiesResources(t *testing.T) {
+	// The stub API server knows only the api deployment in payments
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/apis/apps/v1/namespaces/payments/deployments/api" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"kind": "Deployment", "apiVersion": "apps/v1", "metadata": map[string]string{"name": "api"}})
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(metav1.Status{
+			TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+			Status:   metav1.StatusFailure,
+			Reason:   metav1.StatusReasonNotFound,
+			Code:     http.StatusNotFound,
+		})
+	}))
+	defer server.Close()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create clientset: %v", err)
+	}
+
+	analysis := &llm.Analysis{KubectlCommands: []string{
+		"kubectl describe deploy/api",
+		"kubectl describe deploy/web",
+		"kubectl describe deploy/<name>",
+		"kubectl create configmap settings --from-literal=a=b",
+	}}
+	report := ApplyCommandPolicy(context.Background(), analysis, CommandPolicy{Clientset: clientset, Namespace: "payments"})
+
+	want := []string{
+		"kubectl describe deploy/api",
+		"kubectl describe deploy/web  # deployment/web not found",
+		"kubectl describe deploy/<name>",
+		"kubectl create configmap settings --from-literal=a=b  # changes the cluster",
+	}
+	if !reflect.DeepEqual(analysis.KubectlCommands, want) {
+		t.Errorf("KubectlCommands = %q, want %q", analysis.KubectlCommands, want)
+	}
+	if report.Missing != 1 {
+		t.Errorf("Missing = %d, want 1", report.Missing)
+	}
+	if len(paths) != 2 {
+		t.Errorf("looked up %v, want only the two named deployments", paths)
+	}
+}
@@ -0,0 +1,174 @@
+package guard
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+)
+
+// Pattern is one kind of text that tries to instruct the model
+type Pattern struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// chatMarkup imitates the role markup of chat models; in the output it means the
+// model echoed an injected prompt
+var chatMarkup = Pattern{
+	Name:    "chat-markup",
+	Pattern: regexp.MustCompile(`(?i)<\|(?:im_start|im_end|system|assistant|user)\|>|\[/?INST\]|</?(?:system|assistant|instructions?)>`),
+}
+
+// builtinPatterns match phrasing aimed at an LLM rather than at a human reading logs
+var builtinPatterns = []Pattern{
+	{
+		Name:    "ignore-instructions",
+		Pattern: regexp.MustCompile(`(?i)\b(?:ignore|disregard|forget|override)\b.{0,30}\b(?:previous|prior|above|earlier|all|any|your|system)\b.{0,20}\b(?:instructions?|prompts?|rules|directives|guidelines)\b`),
+	},
+	{
+		Name:    "role-override",
+		Pattern: regexp.MustCompile(`(?i)\byou are (?:now|no longer)\b|\bpretend (?:to be|you are)\b|\bfrom now on,? you\b`),
+	},
+	{
+		Name:    "prompt-reference",
+		Pattern: regexp.MustCompile(`(?i)\b(?:system prompt|new instructions|developer mode|jailbreak)\b`),
+	},
+	chatMarkup,
+	{
+		Name:    "model-addressed",
+		Pattern: regexp.MustCompile(`(?i)\b(?:dear|attention|note to|hey)\s+(?:ai|llm|assistant|language model|chatgpt|claude|gemini)\b|\b(?:ai|llm) (?:assistant|model)s? (?:must|should)\b`),
+	},
+	{
+		Name:    "output-steering",
+		Pattern: regexp.MustCompile(`(?i)(?:^|[.:!]\s*)(?:respond|reply|answer)\s+(?:only\s+)?with\b|\b(?:set|report)\s+(?:the\s+)?(?:severity|root\s?cause|confidence)\s+(?:to|as)\b`),
+	},
+}
+
+// unsafeCommands are never a reasonable fix for a pod incident
+var unsafeCommands = []Pattern{
+	{Name: "pipe-to-shell", Pattern: regexp.MustCompile(`(?i)\b(?:curl|wget)\b[^|;&]*\|\s*(?:sudo\s+)?(?:ba|z|k)?sh\b`)},
+	{Name: "decode-and-run", Pattern: regexp.MustCompile(`(?i)base64\s+(?:-d|--decode)\b.*\|\s*(?:ba|z)?sh\b`)},
+	{Name: "reverse-shell", Pattern: regexp.MustCompile(`/dev/tcp/|\bnc\b.*\s-e\s|\bncat\b.*--exec`)},
+	{Name: "wipe-root", Pattern: regexp.MustCompile(`\brm\s+-[a-zA-Z]*r[a-zA-Z]*f?[a-zA-Z]*\s+/(?:\s|$|\*)`)},
+	{Name: "secret-exfiltration", Pattern: regexp.MustCompile(`(?i)\bkubectl\b.*\bget\s+secrets?\b.*(?:-o\s*(?:yaml|json)|--output).*\|`)},
+}
+
+var (
+	urlRe       = regexp.MustCompile(`(?i)\bhttps?://([a-z0-9.-]+)`)
+	namespaceRe = regexp.MustCompile(`(?:\s-n|--namespace)[=\s]+([a-z0-9][a-z0-9-]*)`)
+)
+
+// defaultAllowedDomains are documentation sites models commonly and legitimately cite
+var defaultAllowedDomains = []string{"kubernetes.io", "k8s.io"}
+
+// systemNamespaces may be inspected for any incident (DNS, CNI, kube-proxy)
+var systemNamespaces = map[string]bool{"default": true, "kube-system": true}
+
+// Finding is text in the incident context that looks like a prompt injection
+type Finding struct {
+	Section string
+	Line    int
+	Pattern string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s line %d: %s", f.Section, f.Line, f.Pattern)
+}
+
+// Guard flags prompt injections in the incident context and checks the model
+// output for instructions that did not come from the incident
+type Guard struct {
+	patterns       []Pattern
+	allowedDomains []string
+}
+
+// New creates a guard with the built-in patterns plus those in cfg
+func New(cfg *config.InjectionConfig) (*Guard, error) {
+	g := &Guard{
+		patterns:       append([]Pattern{}, builtinPatterns...),
+		allowedDomains: append(append([]string{}, defaultAllowedDomains...), cfg.AllowedDomains...),
+	}
+	for i, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid injection pattern %d: %w", i, err)
+		}
+		g.patterns = append(g.patterns, Pattern{Name: fmt.Sprintf("custom-%d", i), Pattern: re})
+	}
+	return g, nil
+}
+
+// Scan returns the lines of text that match an injection pattern
+func (g *Guard) Scan(section, text string) []Finding {
+	var findings []Finding
+	for i, line := range strings.Split(text, "\n") {
+		for _, p := range g.patterns {
+			if p.Pattern.MatchString(line) {
+				findings = append(findings, Finding{Section: section, Line: i + 1, Pattern: p.Name})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// Check returns the reasons to reject an analysis: echoed chat markup, commands
+// that are never a fix, and links, hosts or namespaces that appear nowhere in
+// the prompt the model was given. Phrases like "ignore previous instructions"
+// are not checked, since a good analysis may quote them when reporting an attack.
+func (g *Guard) Check(analysis *llm.Analysis, context string) []string {
+	var fields []string
+	fields = append(fields, analysis.RootCause, analysis.Prevention)
+	fields = append(fields, analysis.ImmediateFix...)
+	fields = append(fields, analysis.KubectlCommands...)
+	if !analysis.Structured {
+		fields = append(fields, analysis.Raw)
+	}
+
+	reasons := map[string]bool{}
+	context = strings.ToLower(context)
+	for _, field := range fields {
+		if chatMarkup.Pattern.MatchString(field) {
+			reasons["contains chat markup from an injected prompt"] = true
+		}
+		for _, p := range unsafeCommands {
+			if p.Pattern.MatchString(field) {
+				reasons["suggests an unsafe command ("+p.Name+")"] = true
+			}
+		}
+		for _, m := range urlRe.FindAllStringSubmatch(field, -1) {
+			host := strings.ToLower(strings.TrimSuffix(m[1], "."))
+			if !g.allowedHost(host) && !strings.Contains(context, host) {
+				reasons["links to "+host+", which is not in the incident context"] = true
+			}
+		}
+	}
+	for _, command := range analysis.KubectlCommands {
+		for _, m := range namespaceRe.FindAllStringSubmatch(command, -1) {
+			if !systemNamespaces[m[1]] && !strings.Contains(context, m[1]) {
+				reasons["targets namespace "+m[1]+", which is not in the incident context"] = true
+			}
+		}
+	}
+
+	list := make([]string, 0, len(reasons))
+	for reason := range reasons {
+		list = append(list, reason)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (g *Guard) allowedHost(host string) bool {
+	for _, domain := range g.allowedDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
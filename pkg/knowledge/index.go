@@ -0,0 +1,98 @@
+package knowledge
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// stopWords are too common in logs and analyses to tell incidents apart
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "that": true,
+	"this": true, "was": true, "not": true, "are": true, "has": true, "have": true,
+	"reason": true, "message": true, "logs": true, "error": true, "info": true,
+	"warn": true, "debug": true, "pod": true, "container": true,
+	// Placeholders left by cache.LogSignature
+	"uuid": true, "hex": true,
+}
+
+// index scores documents against a query by the cosine similarity of their
+// TF-IDF vectors
+type index struct {
+	docs []map[string]float64
+	idf  map[string]float64
+}
+
+func newIndex(docs []string) *index {
+	idx := &index{idf: map[string]float64{}}
+	df := map[string]int{}
+	for _, doc := range docs {
+		tf := termFrequencies(doc)
+		idx.docs = append(idx.docs, tf)
+		for term := range tf {
+			df[term]++
+		}
+	}
+
+	n := float64(len(docs))
+	for term, count := range df {
+		idx.idf[term] = math.Log(1 + n/float64(count))
+	}
+	for _, tf := range idx.docs {
+		idx.weigh(tf)
+	}
+	return idx
+}
+
+// similarity returns the score of every document against query, from 0 to 1
+func (idx *index) similarity(query string) []float64 {
+	q := termFrequencies(query)
+	idx.weigh(q)
+
+	scores := make([]float64, len(idx.docs))
+	for i, doc := range idx.docs {
+		for term, weight := range q {
+			scores[i] += weight * doc[term]
+		}
+	}
+	return scores
+}
+
+// weigh turns term frequencies into a unit-length TF-IDF vector. Terms unknown
+// to the index are dropped since they cannot match any document.
+func (idx *index) weigh(tf map[string]float64) {
+	norm := 0.0
+	for term, freq := range tf {
+		idf, ok := idx.idf[term]
+		if !ok {
+			delete(tf, term)
+			continue
+		}
+		tf[term] = (1 + math.Log(freq)) * idf
+		norm += tf[term] * tf[term]
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for term := range tf {
+		tf[term] /= norm
+	}
+}
+
+// termFrequencies splits text into lower-case words, skipping placeholders
+// left by normalization, numbers and stop words
+func termFrequencies(text string) map[string]float64 {
+	tf := map[string]float64{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.'
+	})
+	for _, word := range words {
+		word = strings.Trim(word, ".")
+		if len(word) < 3 || stopWords[word] || !strings.ContainsFunc(word, unicode.IsLetter) {
+			continue
+		}
+		tf[word]++
+	}
+	return tf
+}
@@ -0,0 +1,84 @@
+package knowledge
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestTermFrequencies(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want map[string]float64
+	}{
+		{
+			name: "lower-cases and counts words",
+			text: "Connection refused; connection REFUSED",
+			want: map[string]float64{"connection": 2, "refused": 2},
+		},
+		{
+			name: "skips stop words, short words and numbers",
+			text: "the pod was OOMKilled at 512 MiB",
+			want: map[string]float64{"oomkilled": 1, "mib": 1},
+		},
+		{
+			name: "keeps dotted and underscored identifiers",
+			text: "missing DATABASE_URL in config.yaml.",
+			want: map[string]float64{"missing": 1, "database_url": 1, "config.yaml": 1},
+		},
+		{
+			name: "drops normalization placeholders",
+			text: "request <uuid> failed at <hex>",
+			want: map[string]float64{"request": 1, "failed": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := termFrequencies(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("termFrequencies(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexSimilarity(t *testing.T) {
+	docs := []string{
+		"CrashLoopBackOff panic: missing DATABASE_URL environment variable",
+		"ImagePullBackOff manifest unknown for registry image tag",
+		"OOMKilled memory limit exceeded by java heap",
+	}
+	idx := newIndex(docs)
+
+	tests := []struct {
+		name  string
+		query string
+		best  int
+	}{
+		{"same words", "panic missing DATABASE_URL", 0},
+		{"partial overlap", "image tag not found in registry", 1},
+		{"single rare term", "java", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := idx.similarity(tt.query)
+			for i, score := range scores {
+				if score < 0 || score > 1+1e-9 {
+					t.Errorf("score[%d] = %v, want within [0, 1]", i, score)
+				}
+				if i != tt.best && score >= scores[tt.best] {
+					t.Errorf("scores = %v, want document %d ranked first", scores, tt.best)
+				}
+			}
+		})
+	}
+
+	if scores := idx.similarity(docs[1]); math.Abs(scores[1]-1) > 1e-9 {
+		t.Errorf("similarity of a document with itself = %v, want 1", scores[1])
+	}
+	if scores := idx.similarity("completely unrelated words"); scores[0] != 0 || scores[1] != 0 || scores[2] != 0 {
+		t.Errorf("scores = %v, want 0 for a query sharing no terms", scores)
+	}
+}
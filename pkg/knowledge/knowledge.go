@@ -0,0 +1,196 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/cache"
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+)
+
+const (
+	// defaultMaxRecords keeps the knowledge base within a ConfigMap (1 MiB)
+	defaultMaxRecords = 200
+	// summaryLines is how many normalized log lines a summary keeps
+	summaryLines = 10
+	// maxSummaryLen bounds the stored summary of a single incident, in characters
+	maxSummaryLen = 1500
+)
+
+// Record is a past incident with the analysis it received
+type Record struct {
+	Fingerprint string `json:"fingerprint"`
+	EventType   string `json:"eventType"`
+	Namespace   string `json:"namespace"`
+	PodName     string `json:"podName"`
+	// Summary is the reason, message and normalized log tail the incident was matched on
+	Summary    string   `json:"summary"`
+	RootCause  string   `json:"rootCause"`
+	Severity   string   `json:"severity"`
+	Fix        []string `json:"fix,omitempty"`
+	Prevention string   `json:"prevention,omitempty"`
+
+	Occurrences int       `json:"occurrences"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+}
+
+// Store persists the knowledge base across analyzer Jobs
+type Store interface {
+	// Update loads the records, applies fn and writes the returned records back
+	// when fn reports a change. Implementations retry fn on concurrent writes.
+	Update(ctx context.Context, fn func(records []*Record) ([]*Record, bool)) error
+}
+
+// Match is a past incident similar to the current one
+type Match struct {
+	Record *Record
+	// Score is the similarity from 0 to 1; 1 is the same fingerprint
+	Score float64
+}
+
+// Base stores analyzed incidents and retrieves the most similar ones
+type Base struct {
+	store      Store
+	maxRecords int
+}
+
+// New creates a knowledge base over store keeping at most maxRecords incidents
+func New(store Store, maxRecords int) *Base {
+	if maxRecords <= 0 {
+		maxRecords = defaultMaxRecords
+	}
+	return &Base{store: store, maxRecords: maxRecords}
+}
+
+// NewRecord builds the record of an analyzed incident
+func NewRecord(fingerprint, eventType, namespace, podName, summary string, analysis *llm.Analysis) *Record {
+	return &Record{
+		Fingerprint: fingerprint,
+		EventType:   eventType,
+		Namespace:   namespace,
+		PodName:     podName,
+		Summary:     summary,
+		RootCause:   analysis.RootCause,
+		Severity:    string(analysis.Severity),
+		Fix:         analysis.ImmediateFix,
+		Prevention:  analysis.Prevention,
+	}
+}
+
+// Summarize condenses an incident into the text it is indexed and matched by:
+// reason, message and the normalized tail of the logs
+func Summarize(reason, message, logs, podName string) string {
+	var b strings.Builder
+	if reason != "" {
+		fmt.Fprintf(&b, "Reason: %s\n", reason)
+	}
+	if message != "" {
+		fmt.Fprintf(&b, "Message: %s\n", message)
+	}
+
+	signature := strings.Split(cache.LogSignature(logs, podName), "\n")
+	if len(signature) > summaryLines {
+		signature = signature[len(signature)-summaryLines:]
+	}
+	if tail := strings.TrimSpace(strings.Join(signature, "\n")); tail != "" {
+		fmt.Fprintf(&b, "Logs:\n%s\n", tail)
+	}
+
+	summary := strings.TrimSpace(b.String())
+	if runes := []rune(summary); len(runes) > maxSummaryLen {
+		summary = string(runes[:maxSummaryLen])
+	}
+	return summary
+}
+
+// Add stores an analyzed incident. An incident with a known fingerprint
+// replaces the earlier analysis and counts as another occurrence.
+func (b *Base) Add(ctx context.Context, rec *Record) error {
+	now := time.Now()
+	return b.store.Update(ctx, func(records []*Record) ([]*Record, bool) {
+		rec.Occurrences, rec.FirstSeen, rec.LastSeen = 1, now, now
+		for i, existing := range records {
+			if existing.Fingerprint == rec.Fingerprint {
+				rec.Occurrences = existing.Occurrences + 1
+				rec.FirstSeen = existing.FirstSeen
+				records = append(records[:i], records[i+1:]...)
+				break
+			}
+		}
+		records = append(records, rec)
+
+		// Drop the incidents not seen for the longest time
+		if len(records) > b.maxRecords {
+			sort.SliceStable(records, func(i, j int) bool {
+				return records[i].LastSeen.Before(records[j].LastSeen)
+			})
+			records = records[len(records)-b.maxRecords:]
+		}
+		return records, true
+	})
+}
+
+// Search returns up to k past incidents with a similarity of at least minScore,
+// most similar first
+func (b *Base) Search(ctx context.Context, fingerprint, eventType, summary string, k int, minScore float64) ([]Match, error) {
+	var records []*Record
+	err := b.store.Update(ctx, func(stored []*Record) ([]*Record, bool) {
+		records = stored
+		return nil, false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read knowledge base: %w", err)
+	}
+
+	docs := make([]string, len(records))
+	for i, rec := range records {
+		docs[i] = document(rec.EventType, rec.Summary, rec.RootCause)
+	}
+	scores := newIndex(docs).similarity(document(eventType, summary, ""))
+
+	var matches []Match
+	for i, rec := range records {
+		score := scores[i]
+		if rec.Fingerprint == fingerprint {
+			score = 1
+		}
+		if score >= minScore {
+			matches = append(matches, Match{Record: rec, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Record.LastSeen.After(matches[j].Record.LastSeen)
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
+
+// Render formats matches as a prompt section
+func Render(matches []Match) string {
+	var b strings.Builder
+	for _, m := range matches {
+		rec := m.Record
+		fmt.Fprintf(&b, "- %s in %s/%s (seen %dx, last %s, similarity %.2f)\n",
+			rec.EventType, rec.Namespace, rec.PodName, rec.Occurrences, rec.LastSeen.UTC().Format("2006-01-02"), m.Score)
+		fmt.Fprintf(&b, "  Root cause: %s (severity %s)\n", rec.RootCause, rec.Severity)
+		if len(rec.Fix) > 0 {
+			fmt.Fprintf(&b, "  Fix: %s\n", strings.Join(rec.Fix, "; "))
+		}
+	}
+	return b.String()
+}
+
+// document is the indexed text of an incident; the event type is repeated so
+// incidents of the same kind rank higher
+func document(eventType, summary, rootCause string) string {
+	return strings.Join([]string{eventType, eventType, summary, rootCause}, "\n")
+}
@@ -0,0 +1,160 @@
+package knowledge
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/llm"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name     string
+		reason   string
+		message  string
+		logs     string
+		contains []string
+	}{
+		{
+			name:     "reason and message",
+			reason:   "CrashLoopBackOff",
+			message:  "back-off restarting failed container",
+			contains: []string{"Reason: CrashLoopBackOff", "Message: back-off restarting failed container"},
+		},
+		{
+			name:     "keeps the log tail",
+			logs:     strings.Repeat("noise\n", 20) + "fatal: database unreachable\n",
+			contains: []string{"Logs:", "database unreachable"},
+		},
+		{
+			name:    "multi-byte text is cut at a rune boundary",
+			message: strings.Repeat("é", maxSummaryLen),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := Summarize(tt.reason, tt.message, tt.logs, "api-7d9f")
+			if !utf8.ValidString(summary) {
+				t.Errorf("summary is not valid UTF-8: %q", summary)
+			}
+			if n := utf8.RuneCountInString(summary); n > maxSummaryLen {
+				t.Errorf("summary has %d characters, want at most %d", n, maxSummaryLen)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(summary, want) {
+					t.Errorf("summary does not contain %q:\n%s", want, summary)
+				}
+			}
+		})
+	}
+}
+
+func testRecord(fingerprint, eventType, summary, rootCause string) *Record {
+	return NewRecord(fingerprint, eventType, "default", "api-7d9f", summary, &llm.Analysis{RootCause: rootCause, Severity: llm.SeverityHigh})
+}
+
+func TestBaseAdd(t *testing.T) {
+	ctx := context.Background()
+	base := New(NewFileStore(filepath.Join(t.TempDir(), "incidents.json")), 2)
+
+	for _, rec := range []*Record{
+		testRecord("a", "CrashLoopBackOff", "missing DATABASE_URL", "env var not set"),
+		testRecord("b", "ImagePullBackOff", "manifest unknown", "bad tag"),
+		testRecord("a", "CrashLoopBackOff", "missing DATABASE_URL", "secret not mounted"),
+	} {
+		if err := base.Add(ctx, rec); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	matches, err := base.Search(ctx, "a", "CrashLoopBackOff", "missing DATABASE_URL", 5, 0)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("Search returned %d records, want 2", len(matches))
+	}
+	rec := matches[0].Record
+	if rec.Fingerprint != "a" || rec.Occurrences != 2 || rec.RootCause != "secret not mounted" {
+		t.Errorf("record = %+v, want the latest analysis seen twice", rec)
+	}
+	if rec.FirstSeen.After(rec.LastSeen) {
+		t.Errorf("FirstSeen %v after LastSeen %v", rec.FirstSeen, rec.LastSeen)
+	}
+
+	if err := base.Add(ctx, testRecord("c", "OOMKilled", "heap exhausted", "limit too low")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	matches, err = base.Search(ctx, "", "OOMKilled", "", 5, 0)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	for _, m := range matches {
+		if m.Record.Fingerprint == "b" {
+			t.Error("least recently seen record kept beyond maxRecords")
+		}
+	}
+}
+
+func TestBaseSearch(t *testing.T) {
+	ctx := context.Background()
+	base := New(NewFileStore(filepath.Join(t.TempDir(), "incidents.json")), 0)
+	for _, rec := range []*Record{
+		testRecord("db", "CrashLoopBackOff", "panic: missing DATABASE_URL", "env var not set"),
+		testRecord("img", "ImagePullBackOff", "manifest unknown for registry tag", "bad tag"),
+		testRecord("oom", "OOMKilled", "java heap exhausted", "limit too low"),
+	} {
+		if err := base.Add(ctx, rec); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		fingerprint string
+		eventType   string
+		summary     string
+		k           int
+		minScore    float64
+		want        []string
+	}{
+		{"same fingerprint scores 1", "img", "CrashLoopBackOff", "unrelated", 1, 0.99, []string{"img"}},
+		{"similar summary ranks first", "", "CrashLoopBackOff", "panic: DATABASE_URL is missing", 1, 0.1, []string{"db"}},
+		{"minimum score filters", "", "Evicted", "disk pressure on node", 3, 0.1, nil},
+		{"k bounds the matches", "", "OOMKilled", "", 2, 0, []string{"oom", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := base.Search(ctx, tt.fingerprint, tt.eventType, tt.summary, tt.k, tt.minScore)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(matches) != len(tt.want) {
+				t.Fatalf("Search returned %d matches, want %d", len(matches), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if want != "" && matches[i].Record.Fingerprint != want {
+					t.Errorf("match %d = %s, want %s", i, matches[i].Record.Fingerprint, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	rec := testRecord("a", "CrashLoopBackOff", "missing DATABASE_URL", "env var not set")
+	rec.Occurrences = 3
+	rec.Fix = []string{"set DATABASE_URL", "restart the deployment"}
+
+	got := Render([]Match{{Record: rec, Score: 0.87}})
+	for _, want := range []string{"CrashLoopBackOff in default/api-7d9f", "seen 3x", "similarity 0.87", "Root cause: env var not set (severity high)", "Fix: set DATABASE_URL; restart the deployment"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render() does not contain %q:\n%s", want, got)
+		}
+	}
+}
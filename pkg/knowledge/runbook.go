@@ -0,0 +1,279 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// maxChunkLen bounds a runbook section; longer sections are split at paragraphs
+const maxChunkLen = 2000
+
+// patternBoost is added to the similarity of every section of a runbook whose
+// error patterns match the incident
+const patternBoost = 0.5
+
+// RunbookSection is the part of a Markdown runbook under one heading
+type RunbookSection struct {
+	// Source is the file or ConfigMap key the runbook was loaded from
+	Source  string
+	Title   string
+	Heading string
+	Content string
+
+	patterns []*regexp.Regexp
+}
+
+// Name is the runbook title and section heading
+func (s *RunbookSection) Name() string {
+	if s.Heading == "" || s.Heading == s.Title {
+		return s.Title
+	}
+	return s.Title + " › " + s.Heading
+}
+
+// RunbookMatch is a runbook section relevant to the incident
+type RunbookMatch struct {
+	Section *RunbookSection
+	Score   float64
+}
+
+// Runbooks is an index over runbook sections
+type Runbooks struct {
+	sections []*RunbookSection
+	index    *index
+}
+
+// frontMatter is the optional YAML header of a runbook:
+//
+//	---
+//	title: Database connection failures
+//	patterns: ["connection refused", "too many clients"]
+//	---
+type frontMatter struct {
+	Title string `yaml:"title"`
+	// Patterns are case-insensitive regular expressions matched against the incident
+	Patterns []string `yaml:"patterns"`
+}
+
+// NewRunbooks chunks and indexes Markdown documents keyed by their source
+func NewRunbooks(docs map[string]string) *Runbooks {
+	sources := make([]string, 0, len(docs))
+	for source := range docs {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+
+	r := &Runbooks{}
+	for _, source := range sources {
+		r.sections = append(r.sections, chunkRunbook(source, docs[source])...)
+	}
+
+	texts := make([]string, len(r.sections))
+	for i, s := range r.sections {
+		texts[i] = s.Title + "\n" + s.Heading + "\n" + s.Content
+	}
+	r.index = newIndex(texts)
+	return r
+}
+
+// Len returns the number of indexed sections
+func (r *Runbooks) Len() int {
+	return len(r.sections)
+}
+
+// Search returns up to k sections relevant to the incident text with a score of
+// at least minScore, best first. Runbooks whose patterns match are boosted.
+func (r *Runbooks) Search(text string, k int, minScore float64) []RunbookMatch {
+	scores := r.index.similarity(text)
+
+	var matches []RunbookMatch
+	for i, s := range r.sections {
+		score := scores[i]
+		for _, re := range s.patterns {
+			if re.MatchString(text) {
+				score += patternBoost
+				break
+			}
+		}
+		if score > 1 {
+			score = 1
+		}
+		if score >= minScore {
+			matches = append(matches, RunbookMatch{Section: s, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// RenderRunbooks formats matches as a prompt section
+func RenderRunbooks(matches []RunbookMatch) string {
+	var b strings.Builder
+	for _, m := range matches {
+		fmt.Fprintf(&b, "### %s\n%s\n\n", m.Section.Name(), m.Section.Content)
+	}
+	return b.String()
+}
+
+// LoadRunbookDir reads the Markdown files under dir, e.g. a Git-synced volume
+func LoadRunbookDir(dir string) (map[string]string, error) {
+	docs := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip hidden directories such as .git
+		if d.IsDir() && path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !isMarkdown(path) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		docs[rel] = string(data)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runbooks from %s: %w", dir, err)
+	}
+	return docs, nil
+}
+
+// LoadRunbookConfigMaps reads the Markdown keys of the ConfigMaps matching selector
+func LoadRunbookConfigMaps(ctx context.Context, clientset *kubernetes.Clientset, namespace, selector string) (map[string]string, error) {
+	list, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runbook configmaps: %w", err)
+	}
+
+	docs := map[string]string{}
+	for _, cm := range list.Items {
+		for key, data := range cm.Data {
+			if isMarkdown(key) {
+				docs[cm.Name+"/"+key] = data
+			}
+		}
+	}
+	return docs, nil
+}
+
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+var headingRe = regexp.MustCompile(`^(#{1,3})\s+(.+?)\s*#*\s*$`)
+
+// chunkRunbook splits a document at its level 1-3 headings. The title comes
+// from the front matter, the first level 1 heading or the source name.
+func chunkRunbook(source, text string) []*RunbookSection {
+	meta, body := parseFrontMatter(source, text)
+
+	var patterns []*regexp.Regexp
+	for _, p := range meta.Patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			klog.Warningf("Ignoring invalid pattern %q in runbook %s: %v", p, source, err)
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+
+	title := meta.Title
+	var sections []*RunbookSection
+	heading := ""
+	var lines []string
+	flush := func() {
+		content := strings.TrimSpace(strings.Join(lines, "\n"))
+		lines = nil
+		if content == "" {
+			return
+		}
+		for _, part := range splitChunk(content) {
+			sections = append(sections, &RunbookSection{Source: source, Heading: heading, Content: part, patterns: patterns})
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if m := headingRe.FindStringSubmatch(line); m != nil && !inFence {
+			flush()
+			heading = m[2]
+			if len(m[1]) == 1 && title == "" {
+				title = heading
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+	for _, s := range sections {
+		s.Title = title
+	}
+	return sections
+}
+
+func parseFrontMatter(source, text string) (frontMatter, string) {
+	var meta frontMatter
+	if !strings.HasPrefix(text, "---\n") {
+		return meta, text
+	}
+	end := strings.Index(text[4:], "\n---")
+	if end < 0 {
+		return meta, text
+	}
+	if err := yaml.Unmarshal([]byte(text[4:4+end]), &meta); err != nil {
+		klog.Warningf("Ignoring invalid front matter in runbook %s: %v", source, err)
+	}
+	return meta, text[4+end+len("\n---"):]
+}
+
+// splitChunk cuts content longer than maxChunkLen at paragraph boundaries
+func splitChunk(content string) []string {
+	if len(content) <= maxChunkLen {
+		return []string{content}
+	}
+
+	var parts []string
+	var b strings.Builder
+	for _, para := range strings.Split(content, "\n\n") {
+		if b.Len() > 0 && b.Len()+len("\n\n")+len(para) > maxChunkLen {
+			parts = append(parts, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(para)
+	}
+	if b.Len() > 0 {
+		parts = append(parts, b.String())
+	}
+	return parts
+}
@@ -0,0 +1,156 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkRunbook(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		text     string
+		title    string
+		headings []string
+		patterns int
+	}{
+		{
+			name:     "title from the first level 1 heading",
+			source:   "db.md",
+			text:     "# Database\nintro\n## Connection refused\ncheck the service\n### Too many clients\nraise max_connections\n",
+			title:    "Database",
+			headings: []string{"Database", "Connection refused", "Too many clients"},
+		},
+		{
+			name:     "title from front matter",
+			source:   "db.md",
+			text:     "---\ntitle: Postgres\npatterns: [\"connection refused\", \"(\"]\n---\n# Database\nintro\n",
+			title:    "Postgres",
+			headings: []string{"Database"},
+			patterns: 1,
+		},
+		{
+			name:     "title from the source name",
+			source:   "ops/oom-killed.md",
+			text:     "## Memory\nraise the limit\n",
+			title:    "oom-killed",
+			headings: []string{"Memory"},
+		},
+		{
+			name:     "headings inside code fences are content",
+			source:   "shell.md",
+			text:     "# Shell\n```sh\n# not a heading\nkubectl get pods\n```\n",
+			title:    "Shell",
+			headings: []string{"Shell"},
+		},
+		{
+			name:     "empty sections are dropped",
+			source:   "empty.md",
+			text:     "# Empty\n## Nothing here\n\n## Steps\nrestart\n",
+			title:    "Empty",
+			headings: []string{"Steps"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections := chunkRunbook(tt.source, tt.text)
+			var headings []string
+			for _, s := range sections {
+				headings = append(headings, s.Heading)
+				if s.Title != tt.title {
+					t.Errorf("Title = %q, want %q", s.Title, tt.title)
+				}
+				if len(s.patterns) != tt.patterns {
+					t.Errorf("section %q has %d patterns, want %d", s.Heading, len(s.patterns), tt.patterns)
+				}
+			}
+			if !reflect.DeepEqual(headings, tt.headings) {
+				t.Errorf("headings = %q, want %q", headings, tt.headings)
+			}
+		})
+	}
+}
+
+func TestSplitChunk(t *testing.T) {
+	para := strings.Repeat("x", maxChunkLen/2-1)
+	parts := splitChunk(strings.Join([]string{para, para, para}, "\n\n"))
+	if len(parts) != 2 {
+		t.Fatalf("split into %d parts, want 2", len(parts))
+	}
+	for i, part := range parts {
+		if len(part) > maxChunkLen {
+			t.Errorf("part %d has %d bytes, want at most %d", i, len(part), maxChunkLen)
+		}
+	}
+	if parts := splitChunk("short"); len(parts) != 1 || parts[0] != "short" {
+		t.Errorf("splitChunk(short) = %q, want it unchanged", parts)
+	}
+}
+
+func TestRunbooksSearch(t *testing.T) {
+	runbooks := NewRunbooks(map[string]string{
+		"db.md":    "---\npatterns: [\"ECONNREFUSED\"]\n---\n# Database\n## Connection refused\nCheck the postgres service endpoints.\n",
+		"image.md": "# Images\n## Pull failures\nVerify the registry credentials and the image tag.\n",
+	})
+	if runbooks.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", runbooks.Len())
+	}
+
+	tests := []struct {
+		name     string
+		text     string
+		minScore float64
+		want     []string
+	}{
+		{"similar words", "failed to pull image: registry denied credentials", 0.1, []string{"Images › Pull failures"}},
+		{"pattern boost", "connect ECONNREFUSED 10.0.0.5:5432", patternBoost, []string{"Database › Connection refused"}},
+		{"nothing relevant", "disk pressure eviction", 0.1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range runbooks.Search(tt.text, 3, tt.minScore) {
+				got = append(got, m.Section.Name())
+				if m.Score > 1 {
+					t.Errorf("score of %s = %v, want at most 1", m.Section.Name(), m.Score)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRunbookDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"db.md":               "# Database",
+		"team/image.MARKDOWN": "# Images",
+		"notes.txt":           "not a runbook",
+		".git/readme.md":      "hidden",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	docs, err := LoadRunbookDir(dir)
+	if err != nil {
+		t.Fatalf("LoadRunbookDir: %v", err)
+	}
+	want := map[string]string{"db.md": "# Database", filepath.Join("team", "image.MARKDOWN"): "# Images"}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("docs = %v, want %v", docs, want)
+	}
+}
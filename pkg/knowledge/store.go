@@ -0,0 +1,20 @@
+package knowledge
+
+import (
+	"github.com/adiii717/kube-ai-sre-agent/pkg/store"
+	"k8s.io/client-go/kubernetes"
+)
+
+// recordsKey is the ConfigMap data key holding the JSON-encoded records
+const recordsKey = "incidents.json"
+
+// NewFileStore creates a store backed by the file at path, e.g. on a PVC
+// shared by analyzer Jobs
+func NewFileStore(path string) Store {
+	return store.NewFileStore[[]*Record](path, "knowledge base")
+}
+
+// NewConfigMapStore creates a store backed by the named ConfigMap
+func NewConfigMapStore(clientset *kubernetes.Clientset, namespace, name string) Store {
+	return store.NewConfigMapStore[[]*Record](clientset, namespace, name, recordsKey, "knowledge base")
+}
@@ -0,0 +1,174 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultAgentMaxSteps  = 5
+	defaultAgentMaxTokens = 30000
+)
+
+// ToolExecutor runs a tool call and returns its result as text
+type ToolExecutor func(ctx context.Context, call ToolCall) (string, error)
+
+// AgentOptions bounds an agentic analysis
+type AgentOptions struct {
+	// MaxSteps is the number of model turns that may call tools
+	MaxSteps int
+	// MaxTokens caps input plus output tokens across turns. Tool calls stop while
+	// a final answer still fits, and no turn is started that would exceed it.
+	MaxTokens int
+}
+
+// Agent runs a multi-turn analysis in which the model may call read-only tools
+type Agent struct {
+	caller  ToolCaller
+	tools   []Tool
+	execute ToolExecutor
+	opts    AgentOptions
+}
+
+// NewAgent creates an agent over a client that supports function calling
+func NewAgent(client Client, tools []Tool, execute ToolExecutor, opts AgentOptions) (*Agent, error) {
+	caller, ok := client.(ToolCaller)
+	if !ok {
+		return nil, fmt.Errorf("client %T does not support tool calling", client)
+	}
+	if opts.MaxSteps <= 0 {
+		opts.MaxSteps = defaultAgentMaxSteps
+	}
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = defaultAgentMaxTokens
+	}
+	return &Agent{caller: caller, tools: tools, execute: execute, opts: opts}, nil
+}
+
+// Analyze lets the model call tools until it answers or a budget runs out, then
+// parses its final answer like Client.Analyze does
+func (a *Agent) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	conv := &ConverseRequest{
+		System:    req.agentSystemPrompt(),
+		Messages:  []Message{{Role: RoleUser, Content: req.Prompt}},
+		Tools:     a.tools,
+		EventType: req.EventType,
+	}
+
+	var usage Usage
+	var calls []string
+	var last *Turn
+	// The next request is projected from the input the provider reported for
+	// the last one plus an estimate of the messages appended since, and the
+	// largest output and batch of tool results seen so far
+	var lastInput, appended, maxOutput, maxResults int
+	for step := 0; ; step++ {
+		conv.DisableTools = step >= a.opts.MaxSteps
+		if step > 0 {
+			next := lastInput + appended + maxOutput
+			if usage.Total()+next > a.opts.MaxTokens {
+				klog.Warningf("Agent token budget of %d would be exceeded after %d steps, stopping without a final answer", a.opts.MaxTokens, step)
+				return a.result(last, fmt.Sprintf("The agent token budget of %d tokens ran out before the model gave a final answer.", a.opts.MaxTokens), calls, usage), nil
+			}
+			// A tool turn must leave room for the final answer that follows it
+			final := next + maxResults + maxOutput
+			conv.DisableTools = conv.DisableTools || usage.Total()+next+final > a.opts.MaxTokens
+		}
+		if conv.DisableTools {
+			klog.Infof("Agent budget reached after %d steps and %d tokens, requesting final answer", step, usage.Total())
+		}
+
+		turn, err := a.caller.Converse(ctx, conv)
+		if err != nil {
+			return nil, err
+		}
+		turn.Usage.Provider = turn.Provider
+		turn.Usage.Model = turn.Model
+		usage.Add(turn.Usage)
+		conv.Messages = append(conv.Messages, turn.Message)
+		last = turn
+
+		if len(turn.Message.ToolCalls) == 0 {
+			return a.result(turn, turn.Message.Content, calls, usage), nil
+		}
+		if conv.DisableTools {
+			return nil, fmt.Errorf("model kept calling tools after the agent budget was exhausted")
+		}
+
+		lastInput = turn.Usage.InputTokens
+		if turn.Usage.OutputTokens > maxOutput {
+			maxOutput = turn.Usage.OutputTokens
+		}
+		appended = estimateMessage(turn.Message)
+		results := 0
+		for _, call := range turn.Message.ToolCalls {
+			desc := describeCall(call)
+			calls = append(calls, desc)
+
+			result := Message{Role: RoleTool, ToolCallID: call.ID, ToolName: call.Name}
+			output, err := a.execute(ctx, call)
+			if err != nil {
+				klog.Warningf("Tool %s failed: %v", desc, err)
+				result.Content = err.Error()
+				result.IsError = true
+			} else {
+				klog.Infof("Tool %s returned %d bytes", desc, len(output))
+				result.Content = output
+			}
+			conv.Messages = append(conv.Messages, result)
+			results += estimateMessage(result)
+		}
+		appended += results
+		if results > maxResults {
+			maxResults = results
+		}
+	}
+}
+
+// result parses the model's final text like Client.Analyze does
+func (a *Agent) result(turn *Turn, text string, calls []string, usage Usage) *Analysis {
+	analysis := ParseAnalysis(text)
+	analysis.Provider = turn.Provider
+	analysis.Model = turn.Model
+	analysis.FallbackFrom = turn.FallbackFrom
+	analysis.ToolCalls = calls
+	analysis.Usage = usage
+	return analysis
+}
+
+// estimateMessage approximates the tokens a message adds to the conversation
+func estimateMessage(m Message) int {
+	tokens := EstimateTokens("", m.Content)
+	for _, call := range m.ToolCalls {
+		tokens += EstimateTokens("", call.Name+string(call.arguments()))
+	}
+	return tokens
+}
+
+// describeCall renders a call as name(key=value, ...) for logs and notifications
+func describeCall(call ToolCall) string {
+	var args map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(call.arguments()))
+	dec.UseNumber()
+	if err := dec.Decode(&args); err != nil || len(args) == 0 {
+		return call.Name + "()"
+	}
+
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, args[k]))
+	}
+	return fmt.Sprintf("%s(%s)", call.Name, strings.Join(parts, ", "))
+}
@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scriptedCaller replies with the scripted turns in order and records the requests
+type scriptedCaller struct {
+	turns    []Message
+	requests []ConverseRequest
+}
+
+func (c *scriptedCaller) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	return nil, errors.New("not used")
+}
+
+func (c *scriptedCaller) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	c.requests = append(c.requests, *req)
+	msg := c.turns[0]
+	if len(c.turns) > 1 {
+		c.turns = c.turns[1:]
+	}
+	return &Turn{Message: msg, Provider: ProviderClaude, Model: "claude-test", Usage: Usage{InputTokens: 100, OutputTokens: 10}}, nil
+}
+
+func toolCall(id, name, args string) Message {
+	return Message{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: id, Name: name, Arguments: json.RawMessage(args)}}}
+}
+
+const finalAnswer = `{"rootCause": "missing secret", "severity": "high", "confidence": 0.9}`
+
+func TestAgentAnalyze(t *testing.T) {
+	caller := &scriptedCaller{turns: []Message{
+		toolCall("1", "get_pod_events", `{"pod": "web-1"}`),
+		toolCall("2", "get_secret_names", ``),
+		{Role: RoleAssistant, Content: finalAnswer},
+	}}
+	execute := func(ctx context.Context, call ToolCall) (string, error) {
+		if call.Name == "get_secret_names" {
+			return "", errors.New("forbidden")
+		}
+		return "Warning FailedMount secret db-creds not found", nil
+	}
+
+	agent, err := NewAgent(caller, []Tool{{Name: "get_pod_events"}, {Name: "get_secret_names"}}, execute, AgentOptions{})
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	analysis, err := agent.Analyze(context.Background(), &Request{Prompt: "pod stuck", EventType: "FailedMount"})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	if analysis.RootCause != "missing secret" {
+		t.Errorf("RootCause = %q", analysis.RootCause)
+	}
+	if want := []string{"get_pod_events(pod=web-1)", "get_secret_names()"}; !reflect.DeepEqual(analysis.ToolCalls, want) {
+		t.Errorf("ToolCalls = %v, want %v", analysis.ToolCalls, want)
+	}
+	if analysis.Usage.InputTokens != 300 || analysis.Usage.OutputTokens != 30 {
+		t.Errorf("Usage = %+v, want the sum of three turns", analysis.Usage)
+	}
+
+	// The last request carries both tool results, the failed one flagged as an error
+	last := caller.requests[len(caller.requests)-1].Messages
+	var results []Message
+	for _, m := range last {
+		if m.Role == RoleTool {
+			results = append(results, m)
+		}
+	}
+	if len(results) != 2 || results[0].IsError || !results[1].IsError || results[1].Content != "forbidden" {
+		t.Errorf("tool results = %+v", results)
+	}
+}
+
+// growingCaller calls a tool until tools are disabled, reporting input tokens
+// that grow with the conversation like a real provider's
+type growingCaller struct {
+	requests []ConverseRequest
+}
+
+func (c *growingCaller) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	return nil, errors.New("not used")
+}
+
+func (c *growingCaller) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	c.requests = append(c.requests, *req)
+	input := 100
+	for _, m := range req.Messages {
+		input += estimateMessage(m)
+	}
+	msg := toolCall("1", "get_pod_events", `{"pod": "web-1"}`)
+	if req.DisableTools {
+		msg = Message{Role: RoleAssistant, Content: finalAnswer}
+	}
+	return &Turn{Message: msg, Provider: ProviderClaude, Usage: Usage{InputTokens: input, OutputTokens: 20}}, nil
+}
+
+func TestAgentBudget(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          AgentOptions
+		wantToolCalls int
+		wantAnswer    bool
+	}{
+		{"step limit", AgentOptions{MaxSteps: 2, MaxTokens: 100000}, 2, true},
+		{"token limit", AgentOptions{MaxSteps: 10, MaxTokens: 1500}, 4, true},
+		{"tight token limit", AgentOptions{MaxSteps: 10, MaxTokens: 700}, 2, true},
+		{"room for the first turn only", AgentOptions{MaxSteps: 10, MaxTokens: 300}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller := &growingCaller{}
+			execute := func(ctx context.Context, call ToolCall) (string, error) {
+				return strings.Repeat("Warning BackOff restarting failed container\n", 5), nil
+			}
+
+			agent, _ := NewAgent(caller, []Tool{{Name: "get_pod_events"}}, execute, tt.opts)
+			analysis, err := agent.Analyze(context.Background(), &Request{Prompt: "pod stuck"})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if total := analysis.Usage.Total(); total > tt.opts.MaxTokens {
+				t.Errorf("used %d tokens, want at most %d", total, tt.opts.MaxTokens)
+			}
+			if len(analysis.ToolCalls) != tt.wantToolCalls {
+				t.Errorf("ToolCalls = %v, want %d", analysis.ToolCalls, tt.wantToolCalls)
+			}
+			if analysis.Structured != tt.wantAnswer {
+				t.Errorf("Structured = %v, want %v: %s", analysis.Structured, tt.wantAnswer, analysis.Raw)
+			}
+			if tt.wantAnswer && !caller.requests[len(caller.requests)-1].DisableTools {
+				t.Error("final answer requested with tools enabled")
+			}
+		})
+	}
+}
+
+func TestAgentModelIgnoresBudget(t *testing.T) {
+	caller := &scriptedCaller{turns: []Message{toolCall("1", "get_pod_events", `{}`)}}
+	execute := func(ctx context.Context, call ToolCall) (string, error) { return "ok", nil }
+
+	agent, _ := NewAgent(caller, []Tool{{Name: "get_pod_events"}}, execute, AgentOptions{MaxSteps: 1})
+	if _, err := agent.Analyze(context.Background(), &Request{Prompt: "pod stuck"}); err == nil {
+		t.Error("Analyze succeeded although the model kept calling tools")
+	}
+}
+
+func TestNewAgentRequiresToolCalling(t *testing.T) {
+	if _, err := NewAgent(&failingClient{}, nil, nil, AgentOptions{}); err == nil {
+		t.Error("NewAgent accepted a client without tool calling")
+	}
+}
@@ -0,0 +1,314 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity is the impact level reported by the model
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+	SeverityUnknown  Severity = "unknown"
+)
+
+// Analysis is the structured result of a root cause analysis
+type Analysis struct {
+	RootCause       string   `json:"rootCause"`
+	Severity        Severity `json:"severity"`
+	Confidence      float64  `json:"confidence"`
+	ImmediateFix    []string `json:"immediateFix"`
+	KubectlCommands []string `json:"kubectlCommands"`
+	Prevention      string   `json:"prevention"`
+	// Raw is the unmodified model output
+	Raw string `json:"raw,omitempty"`
+	// Structured is false when the model did not return usable JSON and only Raw is set
+	Structured bool `json:"structured"`
+
+	// Provider and Model identify what produced the analysis
+	Provider Provider `json:"provider,omitempty"`
+	Model    string   `json:"model,omitempty"`
+	// FallbackFrom lists providers that failed before Provider succeeded ("gemini: rate-limited")
+	FallbackFrom []string `json:"fallbackFrom,omitempty"`
+	// ToolCalls lists the tools the model called in agent mode ("get_pod_events(pod=web-1)")
+	ToolCalls []string `json:"toolCalls,omitempty"`
+	// Usage is the tokens consumed to produce the analysis
+	Usage Usage `json:"usage"`
+	// Triage is the first-pass result of a cheaper model when it was escalated
+	Triage           *Analysis `json:"triage,omitempty"`
+	EscalationReason string    `json:"escalationReason,omitempty"`
+	// Samples are the other analyses drawn in consensus mode
+	Samples []*Analysis `json:"samples,omitempty"`
+	// Agreement is the share of consensus samples that found this root cause
+	Agreement float64 `json:"agreement,omitempty"`
+	// Alternatives are the root causes the other consensus samples found
+	Alternatives []string `json:"alternatives,omitempty"`
+}
+
+// rawAnalysis mirrors Analysis but tolerates the type drift models commonly produce
+type rawAnalysis struct {
+	RootCause       json.RawMessage `json:"rootCause"`
+	Severity        json.RawMessage `json:"severity"`
+	Confidence      json.RawMessage `json:"confidence"`
+	ImmediateFix    json.RawMessage `json:"immediateFix"`
+	KubectlCommands json.RawMessage `json:"kubectlCommands"`
+	Prevention      json.RawMessage `json:"prevention"`
+}
+
+var trailingCommaRe = regexp.MustCompile(`,\s*([}\]])`)
+
+// ParseAnalysis extracts a structured analysis from model output, repairing common
+// JSON mistakes. When no usable JSON is found the raw text is returned unstructured.
+func ParseAnalysis(text string) *Analysis {
+	analysis := &Analysis{
+		Raw:      text,
+		Severity: SeverityUnknown,
+	}
+
+	candidate := extractJSON(text)
+	if candidate == "" {
+		return analysis
+	}
+
+	var raw rawAnalysis
+	if err := json.Unmarshal([]byte(candidate), &raw); err != nil {
+		if err := json.Unmarshal([]byte(repairJSON(candidate)), &raw); err != nil {
+			return analysis
+		}
+	}
+
+	analysis.RootCause = decodeString(raw.RootCause)
+	if analysis.RootCause == "" {
+		// Without a root cause the JSON is not worth trusting
+		return analysis
+	}
+	analysis.Severity = normalizeSeverity(decodeString(raw.Severity))
+	analysis.Confidence = decodeConfidence(raw.Confidence)
+	analysis.ImmediateFix = decodeStringList(raw.ImmediateFix)
+	analysis.KubectlCommands = decodeStringList(raw.KubectlCommands)
+	analysis.Prevention = strings.Join(decodeStringList(raw.Prevention), " ")
+	analysis.Structured = true
+
+	return analysis
+}
+
+// String renders the analysis as human-readable text for logs and notifications
+func (a *Analysis) String() string {
+	if !a.Structured {
+		return a.Raw
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Root Cause: %s\n", a.RootCause)
+	fmt.Fprintf(&b, "Severity: %s (confidence %.0f%%)\n", a.Severity, a.Confidence*100)
+
+	if len(a.ImmediateFix) > 0 {
+		b.WriteString("\nImmediate Fix:\n")
+		for i, step := range a.ImmediateFix {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+		}
+	}
+
+	if len(a.KubectlCommands) > 0 {
+		b.WriteString("\nSuggested Commands:\n")
+		for _, cmd := range a.KubectlCommands {
+			fmt.Fprintf(&b, "  $ %s\n", cmd)
+		}
+	}
+
+	if a.Prevention != "" {
+		fmt.Fprintf(&b, "\nPrevention: %s\n", a.Prevention)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Rewrite applies fn to every free-text field of the analysis
+func (a *Analysis) Rewrite(fn func(string) string) {
+	a.RootCause = fn(a.RootCause)
+	a.Prevention = fn(a.Prevention)
+	a.Raw = fn(a.Raw)
+	for i := range a.ImmediateFix {
+		a.ImmediateFix[i] = fn(a.ImmediateFix[i])
+	}
+	for i := range a.KubectlCommands {
+		a.KubectlCommands[i] = fn(a.KubectlCommands[i])
+	}
+	for i := range a.Alternatives {
+		a.Alternatives[i] = fn(a.Alternatives[i])
+	}
+	if a.Triage != nil {
+		a.Triage.Rewrite(fn)
+	}
+	for _, sample := range a.Samples {
+		sample.Rewrite(fn)
+	}
+}
+
+// TotalUsage returns the usage of the analysis including its triage pass and
+// consensus samples
+func (a *Analysis) TotalUsage() Usage {
+	usage := a.Usage
+	for _, part := range a.parts() {
+		u := part.TotalUsage()
+		usage.InputTokens += u.InputTokens
+		usage.OutputTokens += u.OutputTokens
+		usage.CostUSD += u.CostUSD
+	}
+	return usage
+}
+
+// ClearUsage zeroes the usage of the analysis and its parts, e.g. when it is
+// reused and cost nothing this time
+func (a *Analysis) ClearUsage() {
+	a.Usage = Usage{}
+	for _, part := range a.parts() {
+		part.ClearUsage()
+	}
+}
+
+// parts returns the triage pass and consensus samples behind the analysis
+func (a *Analysis) parts() []*Analysis {
+	var parts []*Analysis
+	if a.Triage != nil {
+		parts = append(parts, a.Triage)
+	}
+	return append(parts, a.Samples...)
+}
+
+// extractJSON returns the outermost JSON object in text, ignoring markdown fences and prose
+func extractJSON(text string) string {
+	start := strings.Index(text, "{")
+	if start < 0 {
+		return ""
+	}
+	end := strings.LastIndex(text, "}")
+	if end < start {
+		// Truncated output: let repairJSON close it
+		return text[start:]
+	}
+	return text[start : end+1]
+}
+
+// repairJSON fixes trailing commas and closes strings, arrays and objects left
+// open by truncated output
+func repairJSON(s string) string {
+	s = trailingCommaRe.ReplaceAllString(s, "$1")
+
+	var stack []byte
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	if inString {
+		s += `"`
+	}
+	s = strings.TrimRight(s, " \t\r\n,:")
+	for i := len(stack) - 1; i >= 0; i-- {
+		s += string(stack[i])
+	}
+	return trailingCommaRe.ReplaceAllString(s, "$1")
+}
+
+func decodeString(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(strings.Join(decodeStringList(raw), " "))
+}
+
+// decodeStringList accepts either a JSON string or an array of strings
+func decodeStringList(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+		return result
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if s = strings.TrimSpace(s); s != "" {
+			return []string{s}
+		}
+	}
+	return nil
+}
+
+// decodeConfidence accepts 0.8, "0.8", 80 or "80%" and returns a value in [0, 1]
+func decodeConfidence(raw json.RawMessage) float64 {
+	if len(raw) == 0 {
+		return 0
+	}
+
+	value := strings.Trim(strings.TrimSpace(string(raw)), `"`)
+	percent := strings.HasSuffix(value, "%")
+	value = strings.TrimSuffix(value, "%")
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+	if percent || f > 1 {
+		f /= 100
+	}
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+func normalizeSeverity(s string) Severity {
+	switch sev := Severity(strings.ToLower(strings.TrimSpace(s))); sev {
+	case SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+		return sev
+	default:
+		return SeverityUnknown
+	}
+}
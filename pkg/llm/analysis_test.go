@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAnalysis(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		structured bool
+		want       Analysis
+	}{
+		{
+			name:       "plain JSON",
+			text:       `{"rootCause": "OOM", "severity": "high", "confidence": 0.8, "immediateFix": ["raise the limit"], "kubectlCommands": ["kubectl top pod web"], "prevention": "size limits"}`,
+			structured: true,
+			want: Analysis{RootCause: "OOM", Severity: SeverityHigh, Confidence: 0.8, ImmediateFix: []string{"raise the limit"},
+				KubectlCommands: []string{"kubectl top pod web"}, Prevention: "size limits"},
+		},
+		{
+			name:       "markdown fence and prose",
+			text:       "Here is the analysis:\n```json\n{\"rootCause\": \"bad tag\", \"severity\": \"Medium\"}\n```\nHope this helps.",
+			structured: true,
+			want:       Analysis{RootCause: "bad tag", Severity: SeverityMedium},
+		},
+		{
+			name:       "type drift",
+			text:       `{"rootCause": ["missing", "secret"], "severity": "urgent", "confidence": "85%", "immediateFix": "create the secret", "prevention": ["use", "external-secrets"]}`,
+			structured: true,
+			want:       Analysis{RootCause: "missing secret", Severity: SeverityUnknown, Confidence: 0.85, ImmediateFix: []string{"create the secret"}, Prevention: "use external-secrets"},
+		},
+		{
+			name:       "trailing commas",
+			text:       `{"rootCause": "probe too strict", "immediateFix": ["raise timeoutSeconds",],}`,
+			structured: true,
+			want:       Analysis{RootCause: "probe too strict", Severity: SeverityUnknown, ImmediateFix: []string{"raise timeoutSeconds"}},
+		},
+		{
+			name:       "truncated output",
+			text:       `{"rootCause": "database unreachable", "severity": "critical", "immediateFix": ["check the db serv`,
+			structured: true,
+			want:       Analysis{RootCause: "database unreachable", Severity: SeverityCritical, ImmediateFix: []string{"check the db serv"}},
+		},
+		{
+			name: "no JSON",
+			text: "The pod is crashing because the config is missing.",
+		},
+		{
+			name: "JSON without a root cause",
+			text: `{"severity": "high"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseAnalysis(tt.text)
+			if got.Raw != tt.text {
+				t.Errorf("Raw = %q, want the model output", got.Raw)
+			}
+			if got.Structured != tt.structured {
+				t.Fatalf("Structured = %v, want %v", got.Structured, tt.structured)
+			}
+			if !tt.structured {
+				if got.Severity != SeverityUnknown {
+					t.Errorf("Severity = %q, want unknown", got.Severity)
+				}
+				return
+			}
+
+			tt.want.Raw, tt.want.Structured = tt.text, true
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ParseAnalysis() = %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeConfidence(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want float64
+	}{
+		{`0.7`, 0.7},
+		{`"0.7"`, 0.7},
+		{`70`, 0.7},
+		{`"70%"`, 0.7},
+		{`150`, 1},
+		{`-1`, 0},
+		{`"high"`, 0},
+		{``, 0},
+	}
+
+	for _, tt := range tests {
+		if got := decodeConfidence([]byte(tt.raw)); got != tt.want {
+			t.Errorf("decodeConfidence(%s) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestAnalysisString(t *testing.T) {
+	unstructured := &Analysis{Raw: "free text"}
+	if got := unstructured.String(); got != "free text" {
+		t.Errorf("String() of unstructured analysis = %q, want the raw text", got)
+	}
+
+	analysis := &Analysis{RootCause: "OOM", Severity: SeverityHigh, Confidence: 0.8, KubectlCommands: []string{"kubectl top pod web"}, Structured: true}
+	want := "Root Cause: OOM\nSeverity: high (confidence 80%)\n\nSuggested Commands:\n  $ kubectl top pod web"
+	if got := analysis.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
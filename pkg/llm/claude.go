@@ -1,35 +1,261 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+)
+
+const (
+	claudeDefaultBaseURL   = "https://api.anthropic.com"
+	claudeDefaultModel     = "claude-3-5-sonnet-20241022"
+	claudeDefaultMaxTokens = 1024
+	claudeAPIVersion       = "2023-06-01"
 )
 
 // ClaudeClient implements LLM client for Anthropic Claude
 type ClaudeClient struct {
-	apiKey string
+	apiKey    string
+	model     string
+	maxTokens int
+	baseURL   string
+	transport *transport
 }
 
 // NewClaudeClient creates a new Claude client
-func NewClaudeClient(apiKey string) *ClaudeClient {
-	return &ClaudeClient{
-		apiKey: apiKey,
+func NewClaudeClient(opts Options) *ClaudeClient {
+	c := &ClaudeClient{
+		apiKey:    opts.APIKey,
+		model:     opts.Model,
+		maxTokens: opts.MaxTokens,
+		baseURL:   strings.TrimSuffix(opts.BaseURL, "/"),
+		transport: newTransport(ProviderClaude, opts),
+	}
+	if c.model == "" {
+		c.model = claudeDefaultModel
+	}
+	if c.maxTokens <= 0 {
+		c.maxTokens = claudeDefaultMaxTokens
 	}
+	if c.baseURL == "" {
+		c.baseURL = claudeDefaultBaseURL
+	}
+	return c
+}
+
+type claudeRequest struct {
+	Model     string          `json:"model"`
+	MaxTokens int             `json:"max_tokens"`
+	System    string          `json:"system,omitempty"`
+	Messages  []claudeMessage `json:"messages"`
+}
+
+type claudeMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type claudeResponse struct {
+	Content    []claudeBlock `json:"content"`
+	StopReason string        `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// claudeToolRequest is a Messages API request with tools and content blocks
+type claudeToolRequest struct {
+	Model      string              `json:"model"`
+	MaxTokens  int                 `json:"max_tokens"`
+	System     string              `json:"system,omitempty"`
+	Messages   []claudeToolMessage `json:"messages"`
+	Tools      []claudeTool        `json:"tools,omitempty"`
+	ToolChoice *claudeToolChoice   `json:"tool_choice,omitempty"`
+}
+
+type claudeToolMessage struct {
+	Role    string        `json:"role"`
+	Content []claudeBlock `json:"content"`
+}
+
+// claudeBlock is a text, tool_use or tool_result content block
+type claudeBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type claudeTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type claudeToolChoice struct {
+	Type string `json:"type"`
+}
+
+type claudeErrorResponse struct {
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
 }
 
 // Analyze performs root cause analysis using Claude
-func (c *ClaudeClient) Analyze(eventType, podName, namespace, logs string) (string, error) {
-	// TODO: Implement actual Claude API call
-	prompt := fmt.Sprintf(`Analyze this Kubernetes incident:
+func (c *ClaudeClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	reqBody := claudeRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		System:    req.systemPrompt(),
+		Messages: []claudeMessage{
+			{Role: "user", Content: req.Prompt},
+		},
+	}
 
-Event Type: %s
-Pod: %s/%s
-Logs:
-%s
+	body, err := c.transport.postJSON(ctx, c.baseURL+"/v1/messages", c.headers(), reqBody, decodeClaudeError)
+	if err != nil {
+		return nil, err
+	}
 
-Provide:
-1. Root cause
-2. Immediate fix
-3. Long-term solution`, eventType, namespace, podName, logs)
+	var claudeResp claudeResponse
+	if err := json.Unmarshal(body, &claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
 
-	return fmt.Sprintf("Analysis for %s:\n\nRoot Cause: [Implement Claude API]\nFix: [Pending implementation]\n\nPrompt: %s", eventType, prompt), nil
+	if claudeResp.StopReason == "refusal" {
+		return nil, contentBlockedError(ProviderClaude, claudeResp.StopReason)
+	}
+
+	var text strings.Builder
+	for _, block := range claudeResp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("no response from Claude")
+	}
+
+	analysis := ParseAnalysis(text.String())
+	analysis.Provider = ProviderClaude
+	analysis.Model = c.model
+	analysis.Usage = Usage{
+		Provider:     ProviderClaude,
+		Model:        c.model,
+		InputTokens:  claudeResp.Usage.InputTokens,
+		OutputTokens: claudeResp.Usage.OutputTokens,
+	}
+	return analysis, nil
+}
+
+// Converse runs one turn of a tool-use conversation with Claude
+func (c *ClaudeClient) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	reqBody := claudeToolRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		System:    req.System,
+		Messages:  claudeMessages(req.Messages),
+	}
+	for _, tool := range req.Tools {
+		reqBody.Tools = append(reqBody.Tools, claudeTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.Parameters,
+		})
+	}
+	if req.DisableTools && len(reqBody.Tools) > 0 {
+		reqBody.ToolChoice = &claudeToolChoice{Type: "none"}
+	}
+
+	body, err := c.transport.postJSON(ctx, c.baseURL+"/v1/messages", c.headers(), reqBody, decodeClaudeError)
+	if err != nil {
+		return nil, err
+	}
+
+	var claudeResp claudeResponse
+	if err := json.Unmarshal(body, &claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if claudeResp.StopReason == "refusal" {
+		return nil, contentBlockedError(ProviderClaude, claudeResp.StopReason)
+	}
+
+	msg := Message{Role: RoleAssistant}
+	for _, block := range claudeResp.Content {
+		switch block.Type {
+		case "text":
+			msg.Content += block.Text
+		case "tool_use":
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		}
+	}
+	if msg.Content == "" && len(msg.ToolCalls) == 0 {
+		return nil, fmt.Errorf("no response from Claude")
+	}
+
+	return &Turn{
+		Message:  msg,
+		Provider: ProviderClaude,
+		Model:    c.model,
+		Usage: Usage{
+			InputTokens:  claudeResp.Usage.InputTokens,
+			OutputTokens: claudeResp.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// claudeMessages converts the conversation to content blocks. Tool results must
+// be sent in a user message, so consecutive results are grouped into one.
+func claudeMessages(messages []Message) []claudeToolMessage {
+	var out []claudeToolMessage
+	for _, m := range messages {
+		switch m.Role {
+		case RoleTool:
+			block := claudeBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content, IsError: m.IsError}
+			if n := len(out); n > 0 && out[n-1].Role == "user" && out[n-1].Content[0].Type == "tool_result" {
+				out[n-1].Content = append(out[n-1].Content, block)
+				continue
+			}
+			out = append(out, claudeToolMessage{Role: "user", Content: []claudeBlock{block}})
+		case RoleAssistant:
+			msg := claudeToolMessage{Role: "assistant"}
+			if m.Content != "" {
+				msg.Content = append(msg.Content, claudeBlock{Type: "text", Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				msg.Content = append(msg.Content, claudeBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: call.arguments()})
+			}
+			out = append(out, msg)
+		default:
+			out = append(out, claudeToolMessage{Role: "user", Content: []claudeBlock{{Type: "text", Text: m.Content}}})
+		}
+	}
+	return out
+}
+
+func (c *ClaudeClient) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": claudeAPIVersion,
+	}
+}
+
+func decodeClaudeError(statusCode int, body []byte) *APIError {
+	var errResp claudeErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error == nil {
+		return nil
+	}
+	return &APIError{
+		Type:    errResp.Error.Type,
+		Message: errResp.Error.Message,
+	}
 }
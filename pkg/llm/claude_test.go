@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// stubRequest is a request received by a stub provider server
+type stubRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// stubServer answers every request with status and response and records what it received
+func stubServer(t *testing.T, status int, response string) (*httptest.Server, *stubRequest) {
+	t.Helper()
+	got := &stubRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got.Path = r.URL.Path
+		got.Header = r.Header.Clone()
+		if err := json.Unmarshal(data, &got.Body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+	return server, got
+}
+
+func TestClaudeClientAnalyze(t *testing.T) {
+	response := `{
+		"content": [{"type": "text", "text": "{\"rootCause\": \"missing DATABASE_URL\", \"severity\": \"high\", \"confidence\": 0.8}"}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 120, "output_tokens": 45}
+	}`
+	server, got := stubServer(t, http.StatusOK, response)
+
+	client := NewClaudeClient(Options{APIKey: "sk-test", Model: "claude-test", MaxTokens: 500, BaseURL: server.URL + "/"})
+	analysis, err := client.Analyze(context.Background(), &Request{System: "You are an SRE.", Prompt: "pod crashed"})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	if got.Path != "/v1/messages" {
+		t.Errorf("path = %q, want /v1/messages", got.Path)
+	}
+	if got.Header.Get("x-api-key") != "sk-test" || got.Header.Get("anthropic-version") != claudeAPIVersion {
+		t.Errorf("headers = %v, want the API key and version", got.Header)
+	}
+	if got.Body["model"] != "claude-test" || got.Body["max_tokens"] != float64(500) {
+		t.Errorf("model, max_tokens = %v, %v", got.Body["model"], got.Body["max_tokens"])
+	}
+	if system, _ := got.Body["system"].(string); system == "" {
+		t.Error("system prompt not sent")
+	}
+
+	if analysis.RootCause != "missing DATABASE_URL" || analysis.Severity != SeverityHigh {
+		t.Errorf("analysis = %+v", analysis)
+	}
+	if analysis.Usage.InputTokens != 120 || analysis.Usage.OutputTokens != 45 {
+		t.Errorf("usage = %+v, want 120 in, 45 out", analysis.Usage)
+	}
+	if analysis.Provider != ProviderClaude || analysis.Model != "claude-test" {
+		t.Errorf("provider, model = %s, %s", analysis.Provider, analysis.Model)
+	}
+}
+
+func TestClaudeClientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     error
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"type": "error", "error": {"type": "rate_limit_error", "message": "slow down"}}`, ErrRateLimited},
+		{"overloaded", 529, `{"type": "error", "error": {"type": "overloaded_error", "message": "busy"}}`, ErrOverloaded},
+		{"auth", http.StatusUnauthorized, `{"type": "error", "error": {"type": "authentication_error", "message": "bad key"}}`, ErrAuth},
+		{"refusal", http.StatusOK, `{"content": [], "stop_reason": "refusal"}`, ErrContentBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := stubServer(t, tt.status, tt.response)
+			client := NewClaudeClient(Options{APIKey: "sk-test", BaseURL: server.URL, MaxRetries: -1})
+
+			_, err := client.Analyze(context.Background(), &Request{Prompt: "pod crashed"})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
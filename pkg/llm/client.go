@@ -1,7 +1,13 @@
 package llm
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
 )
 
 // Provider represents an LLM provider
@@ -11,23 +17,196 @@ const (
 	ProviderGemini Provider = "gemini"
 	ProviderClaude Provider = "claude"
 	ProviderOpenAI Provider = "openai"
+	// ProviderOpenAICompatible targets self-hosted servers speaking the OpenAI API
+	ProviderOpenAICompatible Provider = "openai-compatible"
+	// ProviderMock returns canned responses for offline testing
+	ProviderMock Provider = "mock"
 )
 
+// Options contains settings shared by all LLM providers
+type Options struct {
+	APIKey    string
+	Model     string
+	MaxTokens int
+	// BaseURL overrides the provider API endpoint (e.g. for a proxy or a local stub server)
+	BaseURL string
+	// Organization and Project are sent as OpenAI-Organization/OpenAI-Project headers
+	Organization string
+	Project      string
+	// AuthHeader is the header carrying APIKey verbatim; empty means "Authorization: Bearer <key>"
+	AuthHeader string
+
+	// Timeout bounds each HTTP attempt (default 60s)
+	Timeout time.Duration
+	// MaxRetries is the number of retries on 429/5xx (0 means default, negative disables)
+	MaxRetries int
+	// HTTPClient overrides the HTTP client used for requests
+	HTTPClient *http.Client
+
+	// FixturesDir and Responses hold the mock provider's canned model output,
+	// keyed by event type or "default"
+	FixturesDir string
+	Responses   map[string]string
+}
+
 // Client interface for LLM providers
 type Client interface {
-	Analyze(eventType, podName, namespace, logs string) (string, error)
+	Analyze(ctx context.Context, req *Request) (*Analysis, error)
 }
 
 // NewClient creates a new LLM client based on provider
-func NewClient(provider Provider, apiKey string) (Client, error) {
+func NewClient(provider Provider, opts Options) (Client, error) {
 	switch provider {
 	case ProviderGemini:
-		return NewGeminiClient(apiKey), nil
+		return NewGeminiClient(opts), nil
 	case ProviderClaude:
-		return NewClaudeClient(apiKey), nil
+		return NewClaudeClient(opts), nil
 	case ProviderOpenAI:
-		return NewOpenAIClient(apiKey), nil
+		return NewOpenAIClient(opts), nil
+	case ProviderOpenAICompatible:
+		return NewOpenAICompatibleClient(opts)
+	case ProviderMock:
+		return NewMockClient(opts), nil
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
 }
+
+// APIKeyEnvVar is the environment variable holding the API key of the i-th
+// provider in the fallback chain
+func APIKeyEnvVar(index int) string {
+	return fmt.Sprintf("LLM_API_KEY_%d", index)
+}
+
+// NewClientFromConfig creates an LLM client for the provider selected in cfg, or a
+// FallbackClient when cfg.Providers is set. Fallback entries read their key from
+// APIKeyEnvVar(i) and default to apiKey. In record mode the client saves its
+// responses; in replay mode no provider is called at all.
+func NewClientFromConfig(cfg *config.LLMConfig, apiKey string) (Client, error) {
+	switch cfg.Record.Mode {
+	case "":
+		return newProviderClient(cfg, apiKey)
+	case RecordModeRecord, RecordModeReplay:
+		if cfg.Record.Dir == "" {
+			return nil, fmt.Errorf("%s mode requires a directory", cfg.Record.Mode)
+		}
+		if cfg.Record.Mode == RecordModeReplay {
+			return NewReplayClient(cfg.Record.Dir), nil
+		}
+		client, err := newProviderClient(cfg, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewRecordingClient(client, cfg.Record.Dir)
+	default:
+		return nil, fmt.Errorf("unsupported record mode: %s", cfg.Record.Mode)
+	}
+}
+
+// newProviderClient creates the configured provider or fallback chain, wrapped
+// in a TieredClient when tiering is enabled
+func newProviderClient(cfg *config.LLMConfig, apiKey string) (Client, error) {
+	escalation, err := newChainClient(cfg, apiKey)
+	if err != nil || !cfg.Tiering.Enabled {
+		return escalation, err
+	}
+
+	// The triage chain is the same chain with each provider's triage model
+	triageCfg := *cfg
+	triageCfg.Model = map[string]string{}
+	for name, model := range cfg.Model {
+		triageCfg.Model[name] = model
+	}
+	for name, model := range cfg.Tiering.TriageModel {
+		triageCfg.Model[name] = model
+	}
+	triageCfg.Providers = make([]config.ProviderConfig, len(cfg.Providers))
+	for i, entry := range cfg.Providers {
+		if model := cfg.Tiering.TriageModel[entry.Name]; model != "" {
+			entry.Model = model
+		}
+		triageCfg.Providers[i] = entry
+	}
+	if cfg.Tiering.TriageModel[string(PrimaryProvider(cfg))] == "" {
+		return nil, fmt.Errorf("tiering requires a triage model for provider %s", PrimaryProvider(cfg))
+	}
+
+	triage, err := newChainClient(&triageCfg, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create triage client: %w", err)
+	}
+
+	severities := make([]Severity, 0, len(cfg.Tiering.EscalateSeverities))
+	for _, s := range cfg.Tiering.EscalateSeverities {
+		severities = append(severities, normalizeSeverity(s))
+	}
+	return NewTieredClient(triage, escalation, cfg.Tiering.ConfidenceThreshold, severities), nil
+}
+
+func newChainClient(cfg *config.LLMConfig, apiKey string) (Client, error) {
+	if len(cfg.Providers) == 0 {
+		return NewClient(Provider(cfg.Provider), optionsFromConfig(cfg, apiKey))
+	}
+
+	providers := make([]Provider, 0, len(cfg.Providers))
+	clients := make([]Client, 0, len(cfg.Providers))
+	for i, entry := range cfg.Providers {
+		key := os.Getenv(APIKeyEnvVar(i))
+		if key == "" {
+			key = apiKey
+		}
+
+		client, err := NewClient(Provider(entry.Name), entryOptions(cfg, entry, key))
+		if err != nil {
+			return nil, fmt.Errorf("provider %d (%s): %w", i, entry.Name, err)
+		}
+		providers = append(providers, Provider(entry.Name))
+		clients = append(clients, client)
+	}
+
+	return NewFallbackClient(providers, clients)
+}
+
+// entryOptions returns the options of a fallback chain entry, which override
+// the shared settings
+func entryOptions(cfg *config.LLMConfig, entry config.ProviderConfig, apiKey string) Options {
+	opts := optionsFromConfig(cfg, apiKey)
+	opts.Model = cfg.Model[entry.Name]
+	if entry.Model != "" {
+		opts.Model = entry.Model
+	}
+	if entry.MaxTokens > 0 {
+		opts.MaxTokens = entry.MaxTokens
+	}
+	if entry.BaseURL != "" {
+		opts.BaseURL = entry.BaseURL
+	}
+	if entry.AuthHeader != "" {
+		opts.AuthHeader = entry.AuthHeader
+	}
+	return opts
+}
+
+// PrimaryProvider returns the provider tried first for cfg
+func PrimaryProvider(cfg *config.LLMConfig) Provider {
+	if len(cfg.Providers) > 0 {
+		return Provider(cfg.Providers[0].Name)
+	}
+	return Provider(cfg.Provider)
+}
+
+func optionsFromConfig(cfg *config.LLMConfig, apiKey string) Options {
+	return Options{
+		APIKey:       apiKey,
+		Model:        cfg.Model[cfg.Provider],
+		MaxTokens:    cfg.MaxTokens,
+		BaseURL:      cfg.BaseURL,
+		Organization: cfg.OpenAI.Organization,
+		Project:      cfg.OpenAI.Project,
+		AuthHeader:   cfg.AuthHeader,
+		Timeout:      time.Duration(cfg.TimeoutSeconds) * time.Second,
+		MaxRetries:   cfg.MaxRetries,
+		FixturesDir:  cfg.Mock.FixturesDir,
+		Responses:    cfg.Mock.Responses,
+	}
+}
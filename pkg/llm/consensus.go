@@ -0,0 +1,269 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultConsensusSamples = 3
+	defaultMinAgreement     = 0.5
+	// sameCauseThreshold is the word overlap above which two root causes are
+	// taken to describe the same cause
+	sameCauseThreshold = 0.4
+)
+
+// defaultConsensusSeverities trigger sampling when none are configured
+var defaultConsensusSeverities = []Severity{SeverityHigh, SeverityCritical}
+
+// ConsensusClient draws several independent analyses of severe incidents and
+// returns the root cause most of them agree on, with the agreement score and
+// the alternatives
+type ConsensusClient struct {
+	primary      Client
+	samplers     []Client
+	samples      int
+	severities   map[Severity]bool
+	minAgreement float64
+}
+
+// NewConsensusClient creates a consensus client. primary answers first; when
+// its severity is listed, samples-1 more analyses are drawn from samplers in turn.
+func NewConsensusClient(primary Client, samplers []Client, samples int, severities []Severity, minAgreement float64) *ConsensusClient {
+	if samples < 2 {
+		samples = defaultConsensusSamples
+	}
+	if len(severities) == 0 {
+		severities = defaultConsensusSeverities
+	}
+	if minAgreement <= 0 {
+		minAgreement = defaultMinAgreement
+	}
+	c := &ConsensusClient{
+		primary:      primary,
+		samplers:     samplers,
+		samples:      samples,
+		severities:   map[Severity]bool{},
+		minAgreement: minAgreement,
+	}
+	for _, s := range severities {
+		c.severities[s] = true
+	}
+	return c
+}
+
+// WithConsensus wraps client in a ConsensusClient when consensus mode is
+// enabled. Extra samples come from the configured providers, or from the
+// provider chain when none are listed.
+func WithConsensus(cfg *config.LLMConfig, apiKey string, client Client) (Client, error) {
+	if !cfg.Consensus.Enabled || cfg.Record.Mode == RecordModeReplay {
+		return client, nil
+	}
+
+	var samplers []Client
+	for _, name := range cfg.Consensus.Providers {
+		sampler, err := newSampler(cfg, apiKey, name)
+		if err != nil {
+			return nil, fmt.Errorf("consensus provider %s: %w", name, err)
+		}
+		samplers = append(samplers, sampler)
+	}
+	if len(samplers) == 0 {
+		sampler, err := newChainClient(cfg, apiKey)
+		if err != nil {
+			return nil, err
+		}
+		samplers = append(samplers, sampler)
+	}
+
+	severities := make([]Severity, 0, len(cfg.Consensus.Severities))
+	for _, s := range cfg.Consensus.Severities {
+		severities = append(severities, normalizeSeverity(s))
+	}
+	return NewConsensusClient(client, samplers, cfg.Consensus.Samples, severities, cfg.Consensus.MinAgreement), nil
+}
+
+// newSampler creates a single provider client, configured like its entry in
+// the fallback chain when it has one
+func newSampler(cfg *config.LLMConfig, apiKey, name string) (Client, error) {
+	for i, entry := range cfg.Providers {
+		if entry.Name != name {
+			continue
+		}
+		key := os.Getenv(APIKeyEnvVar(i))
+		if key == "" {
+			key = apiKey
+		}
+		return NewClient(Provider(name), entryOptions(cfg, entry, key))
+	}
+	opts := optionsFromConfig(cfg, apiKey)
+	opts.Model = cfg.Model[name]
+	return NewClient(Provider(name), opts)
+}
+
+// Analyze runs the primary client and samples further analyses when the
+// incident is severe enough
+func (c *ConsensusClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	first, err := c.primary.Analyze(ctx, req)
+	if err != nil || !first.Structured || !c.severities[first.Severity] {
+		return first, err
+	}
+
+	klog.Infof("Severity %s, drawing %d more samples for consensus", first.Severity, c.samples-1)
+	extra := make([]*Analysis, c.samples-1)
+	var wg sync.WaitGroup
+	for i := range extra {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sample, err := c.samplers[i%len(c.samplers)].Analyze(ctx, req)
+			if err != nil {
+				klog.Warningf("Consensus sample %d failed (%s): %v", i+2, ClassOf(err), err)
+				return
+			}
+			extra[i] = sample
+		}(i)
+	}
+	wg.Wait()
+
+	all := []*Analysis{first}
+	for _, sample := range extra {
+		if sample != nil && sample.Structured {
+			all = append(all, sample)
+		}
+	}
+	if len(all) == 1 {
+		return first, nil
+	}
+	return consensus(all, c.minAgreement), nil
+}
+
+// Converse delegates to the primary client; tool-use conversations are not sampled
+func (c *ConsensusClient) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	caller, ok := c.primary.(ToolCaller)
+	if !ok {
+		return nil, fmt.Errorf("client %T does not support tool calling", c.primary)
+	}
+	return caller.Converse(ctx, req)
+}
+
+// consensus groups analyses by root cause and returns the most confident
+// analysis of the largest group, carrying the other samples, the agreement
+// score and the root causes of the other groups
+func consensus(all []*Analysis, minAgreement float64) *Analysis {
+	var clusters [][]*Analysis
+	for _, a := range all {
+		joined := false
+		for i, cluster := range clusters {
+			if sameCause(cluster[0].RootCause, a.RootCause) {
+				clusters[i] = append(cluster, a)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			clusters = append(clusters, []*Analysis{a})
+		}
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if len(clusters[i]) != len(clusters[j]) {
+			return len(clusters[i]) > len(clusters[j])
+		}
+		return totalConfidence(clusters[i]) > totalConfidence(clusters[j])
+	})
+
+	result := mostConfident(clusters[0])
+	result.Agreement = float64(len(clusters[0])) / float64(len(all))
+	for _, cluster := range clusters[1:] {
+		result.Alternatives = append(result.Alternatives,
+			fmt.Sprintf("%s (%d of %d)", mostConfident(cluster).RootCause, len(cluster), len(all)))
+	}
+	for _, a := range all {
+		if a != result {
+			result.Samples = append(result.Samples, a)
+		}
+	}
+
+	// A confident answer most samples disagree with is not a confident answer
+	if result.Agreement < minAgreement && result.Confidence > result.Agreement {
+		result.Confidence = result.Agreement
+	}
+	klog.Infof("Consensus: %d of %d samples agree (%.0f%%), %d alternatives",
+		len(clusters[0]), len(all), result.Agreement*100, len(result.Alternatives))
+	return result
+}
+
+func mostConfident(cluster []*Analysis) *Analysis {
+	best := cluster[0]
+	for _, a := range cluster[1:] {
+		if a.Confidence > best.Confidence {
+			best = a
+		}
+	}
+	return best
+}
+
+func totalConfidence(cluster []*Analysis) float64 {
+	total := 0.0
+	for _, a := range cluster {
+		total += a.Confidence
+	}
+	return total
+}
+
+// causeStopWords carry no meaning about the cause itself
+var causeStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "that": true,
+	"this": true, "was": true, "are": true, "has": true, "its": true, "due": true,
+	"because": true, "which": true, "pod": true, "container": true, "application": true,
+}
+
+// sameCause reports whether two root causes share most of their words,
+// measured by the overlap coefficient so a terse and a verbose statement of
+// the same cause still match
+func sameCause(a, b string) bool {
+	wa, wb := causeWords(a), causeWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return false
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	smaller := len(wa)
+	if len(wb) < smaller {
+		smaller = len(wb)
+	}
+	return float64(shared)/float64(smaller) >= sameCauseThreshold
+}
+
+func causeWords(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) < 3 || causeStopWords[w] {
+			continue
+		}
+		// Crude stemming so "limits" matches "limit" and "crashed" matches "crash"
+		for _, suffix := range []string{"ing", "ed", "es", "s"} {
+			if len(w) > len(suffix)+3 && strings.HasSuffix(w, suffix) {
+				w = strings.TrimSuffix(w, suffix)
+				break
+			}
+		}
+		words[w] = true
+	}
+	return words
+}
@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSameCause(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Memory limit too low", "The container exceeded its memory limits and was OOMKilled", true},
+		{"Missing DATABASE_URL environment variable", "DATABASE_URL is not set in the environment", true},
+		{"Image tag does not exist", "Liveness probe times out", false},
+		{"The pod crashed on startup", "Crashing during startup", true},
+		{"The pod crashed", "The container was evicted", false},
+		{"", "anything", false},
+	}
+
+	for _, tt := range tests {
+		if got := sameCause(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameCause(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	sample := func(rootCause string, confidence float64) *Analysis {
+		return &Analysis{RootCause: rootCause, Severity: SeverityHigh, Confidence: confidence, Structured: true}
+	}
+
+	tests := []struct {
+		name             string
+		samples          []*Analysis
+		wantRootCause    string
+		wantAgreement    float64
+		wantConfidence   float64
+		wantAlternatives int
+	}{
+		{
+			name:           "unanimous",
+			samples:        []*Analysis{sample("memory limit too low", 0.7), sample("memory limit is too low for the heap", 0.9), sample("low memory limit", 0.8)},
+			wantRootCause:  "memory limit is too low for the heap",
+			wantAgreement:  1,
+			wantConfidence: 0.9,
+		},
+		{
+			name:             "majority wins over a more confident outlier",
+			samples:          []*Analysis{sample("database password rotated", 0.95), sample("memory limit too low", 0.6), sample("memory limit exceeded", 0.7)},
+			wantRootCause:    "memory limit exceeded",
+			wantAgreement:    2.0 / 3,
+			wantConfidence:   0.7,
+			wantAlternatives: 1,
+		},
+		{
+			name:             "no agreement caps confidence",
+			samples:          []*Analysis{sample("database password rotated", 0.9), sample("memory limit too low", 0.6), sample("image tag missing", 0.5)},
+			wantRootCause:    "database password rotated",
+			wantAgreement:    1.0 / 3,
+			wantConfidence:   1.0 / 3,
+			wantAlternatives: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := consensus(tt.samples, defaultMinAgreement)
+			if got.RootCause != tt.wantRootCause {
+				t.Errorf("RootCause = %q, want %q", got.RootCause, tt.wantRootCause)
+			}
+			if math.Abs(got.Agreement-tt.wantAgreement) > 1e-9 {
+				t.Errorf("Agreement = %v, want %v", got.Agreement, tt.wantAgreement)
+			}
+			if math.Abs(got.Confidence-tt.wantConfidence) > 1e-9 {
+				t.Errorf("Confidence = %v, want %v", got.Confidence, tt.wantConfidence)
+			}
+			if len(got.Alternatives) != tt.wantAlternatives {
+				t.Errorf("Alternatives = %q, want %d", got.Alternatives, tt.wantAlternatives)
+			}
+			if len(got.Samples) != len(tt.samples)-1 {
+				t.Errorf("Samples has %d analyses, want the other %d", len(got.Samples), len(tt.samples)-1)
+			}
+		})
+	}
+}
+
+func TestConsensusClientAnalyze(t *testing.T) {
+	const (
+		high  = `{"rootCause": "memory limit too low", "severity": "high", "confidence": 0.6}`
+		other = `{"rootCause": "memory limit exceeded by the heap", "severity": "high", "confidence": 0.8}`
+		low   = `{"rootCause": "transient probe failure", "severity": "low", "confidence": 0.6}`
+	)
+
+	tests := []struct {
+		name          string
+		primary       string
+		samplers      []*staticClient
+		wantSampled   bool
+		wantRootCause string
+	}{
+		{
+			name:          "low severity is not sampled",
+			primary:       low,
+			samplers:      []*staticClient{{text: other}, {text: other}},
+			wantRootCause: "transient probe failure",
+		},
+		{
+			name:          "high severity is sampled",
+			primary:       high,
+			samplers:      []*staticClient{{text: other}, {text: other}},
+			wantSampled:   true,
+			wantRootCause: "memory limit exceeded by the heap",
+		},
+		{
+			name:          "failed samples are skipped",
+			primary:       high,
+			samplers:      []*staticClient{{err: errors.New("timeout")}, {err: errors.New("timeout")}},
+			wantSampled:   true,
+			wantRootCause: "memory limit too low",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samplers := make([]Client, len(tt.samplers))
+			for i, s := range tt.samplers {
+				samplers[i] = s
+			}
+			client := NewConsensusClient(&staticClient{text: tt.primary}, samplers, 3, nil, 0)
+
+			got, err := client.Analyze(context.Background(), &Request{Prompt: "pod OOMKilled"})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if got.RootCause != tt.wantRootCause {
+				t.Errorf("RootCause = %q, want %q", got.RootCause, tt.wantRootCause)
+			}
+			for i, s := range tt.samplers {
+				if sampled := s.calls > 0; sampled != tt.wantSampled {
+					t.Errorf("sampler %d called %d times, want sampled %v", i, s.calls, tt.wantSampled)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrorClass groups provider errors by how callers should react to them
+type ErrorClass string
+
+const (
+	ErrorClassRateLimited    ErrorClass = "rate-limited"
+	ErrorClassQuota          ErrorClass = "quota-exceeded"
+	ErrorClassAuth           ErrorClass = "auth"
+	ErrorClassContentBlocked ErrorClass = "content-blocked"
+	ErrorClassOverloaded     ErrorClass = "overloaded"
+	ErrorClassUnavailable    ErrorClass = "unavailable"
+	ErrorClassInvalidRequest ErrorClass = "invalid-request"
+	ErrorClassUnknown        ErrorClass = "unknown"
+)
+
+// Sentinel errors matched by errors.Is against an *APIError of the corresponding class
+var (
+	ErrRateLimited    = errors.New("provider rate limit exceeded")
+	ErrQuotaExceeded  = errors.New("provider quota exceeded")
+	ErrAuth           = errors.New("provider authentication failed")
+	ErrContentBlocked = errors.New("provider blocked the content")
+	ErrOverloaded     = errors.New("provider overloaded")
+	ErrUnavailable    = errors.New("provider unavailable")
+)
+
+// APIError is an error response returned by an LLM provider
+type APIError struct {
+	Provider   Provider
+	StatusCode int
+	Type       string
+	Message    string
+	Class      ErrorClass
+	// RetryAfter is the delay requested by the provider, if any
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("%s API error: %s (%s, status %d)", e.Provider, e.Message, e.Type, e.StatusCode)
+	}
+	return fmt.Sprintf("%s API error: %s (status %d)", e.Provider, e.Message, e.StatusCode)
+}
+
+// Is reports whether the API error matches one of the sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.Class == ErrorClassRateLimited
+	case ErrQuotaExceeded:
+		return e.Class == ErrorClassQuota
+	case ErrAuth:
+		return e.Class == ErrorClassAuth
+	case ErrContentBlocked:
+		return e.Class == ErrorClassContentBlocked
+	case ErrOverloaded:
+		return e.Class == ErrorClassOverloaded
+	case ErrUnavailable:
+		return e.Class == ErrorClassUnavailable
+	}
+	return false
+}
+
+// Retryable reports whether the same request may succeed if sent again later
+func (e *APIError) Retryable() bool {
+	switch e.Class {
+	case ErrorClassRateLimited, ErrorClassOverloaded, ErrorClassUnavailable:
+		return true
+	}
+	return false
+}
+
+// ClassOf returns the class of err, or ErrorClassUnknown if it is not an *APIError
+func ClassOf(err error) ErrorClass {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Class
+	}
+	return ErrorClassUnknown
+}
+
+// classify derives the error class from the status code and provider error details
+func classify(statusCode int, errType, message string) ErrorClass {
+	details := strings.ToLower(errType + " " + message)
+
+	switch {
+	// Per-minute limits are also worded as "quota" on 429s, so only hard quota errors
+	// (OpenAI insufficient_quota, non-429 billing errors) are treated as exhausted
+	case strings.Contains(details, "insufficient_quota"),
+		statusCode != http.StatusTooManyRequests && (strings.Contains(details, "quota") || strings.Contains(details, "billing")):
+		return ErrorClassQuota
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden ||
+		strings.Contains(details, "api key") || strings.Contains(details, "authentication"):
+		return ErrorClassAuth
+	case strings.Contains(details, "content_filter") || strings.Contains(details, "content_policy") ||
+		strings.Contains(details, "safety"):
+		return ErrorClassContentBlocked
+	case statusCode == http.StatusTooManyRequests || strings.Contains(details, "rate_limit"):
+		return ErrorClassRateLimited
+	case statusCode == 529 || strings.Contains(details, "overloaded"):
+		return ErrorClassOverloaded
+	case statusCode >= 500:
+		return ErrorClassUnavailable
+	case statusCode >= 400:
+		return ErrorClassInvalidRequest
+	}
+	return ErrorClassUnknown
+}
+
+// contentBlockedError reports a response the provider refused to produce
+func contentBlockedError(provider Provider, reason string) *APIError {
+	return &APIError{
+		Provider:   provider,
+		StatusCode: http.StatusOK,
+		Type:       reason,
+		Message:    "response blocked by provider safety filters",
+		Class:      ErrorClassContentBlocked,
+	}
+}
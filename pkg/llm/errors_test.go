@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status  int
+		errType string
+		message string
+		want    ErrorClass
+	}{
+		{http.StatusTooManyRequests, "rate_limit_exceeded", "Rate limit reached", ErrorClassRateLimited},
+		{http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "Quota exceeded for requests per minute", ErrorClassRateLimited},
+		{http.StatusTooManyRequests, "insufficient_quota", "You exceeded your current quota", ErrorClassQuota},
+		{http.StatusForbidden, "", "billing account disabled", ErrorClassQuota},
+		{http.StatusUnauthorized, "authentication_error", "invalid x-api-key", ErrorClassAuth},
+		{http.StatusBadRequest, "", "API key not valid", ErrorClassAuth},
+		{http.StatusBadRequest, "content_policy_violation", "blocked", ErrorClassContentBlocked},
+		{529, "overloaded_error", "Overloaded", ErrorClassOverloaded},
+		{http.StatusServiceUnavailable, "", "upstream connect error", ErrorClassUnavailable},
+		{http.StatusBadRequest, "invalid_request_error", "max_tokens too large", ErrorClassInvalidRequest},
+		{http.StatusOK, "", "", ErrorClassUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := classify(tt.status, tt.errType, tt.message); got != tt.want {
+			t.Errorf("classify(%d, %q, %q) = %s, want %s", tt.status, tt.errType, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"0", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	future := time.Now().Add(2 * time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got < time.Minute || got > 2*time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v, want about 2m", future, got)
+	}
+}
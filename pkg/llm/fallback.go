@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// FallbackClient tries an ordered list of providers until one produces an analysis
+type FallbackClient struct {
+	entries []fallbackEntry
+}
+
+type fallbackEntry struct {
+	provider Provider
+	client   Client
+}
+
+// NewFallbackClient creates a client that tries clients in order. providers[i]
+// names the provider behind clients[i] and is used in logs and notifications.
+func NewFallbackClient(providers []Provider, clients []Client) (*FallbackClient, error) {
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("fallback chain requires at least one provider")
+	}
+	if len(providers) != len(clients) {
+		return nil, fmt.Errorf("fallback chain has %d providers but %d clients", len(providers), len(clients))
+	}
+
+	f := &FallbackClient{}
+	for i := range clients {
+		f.entries = append(f.entries, fallbackEntry{provider: providers[i], client: clients[i]})
+	}
+	return f, nil
+}
+
+// Analyze tries each provider in order, falling back on transient provider
+// failures. Errors the next provider would repeat, such as an invalid request,
+// and cancellation are returned immediately.
+func (f *FallbackClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	var failures []string
+	var errs []error
+
+	for i, entry := range f.entries {
+		analysis, err := entry.client.Analyze(ctx, req)
+		if err == nil {
+			analysis.Provider = entry.provider
+			analysis.FallbackFrom = failures
+			return analysis, nil
+		}
+
+		if !fallsBack(err) {
+			return nil, err
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+
+		class := ClassOf(err)
+		failures = append(failures, fmt.Sprintf("%s: %s", entry.provider, class))
+		if i < len(f.entries)-1 {
+			klog.Warningf("Provider %s failed (%s), falling back to %s: %v", entry.provider, class, f.entries[i+1].provider, err)
+		}
+	}
+
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// Converse runs a tool-use turn on the first provider that answers. Providers
+// without function calling support are skipped. The conversation is kept in a
+// provider-neutral form, so a later turn may be served by a different provider.
+func (f *FallbackClient) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	var failures []string
+	var errs []error
+
+	for _, entry := range f.entries {
+		caller, ok := entry.client.(ToolCaller)
+		if !ok {
+			continue
+		}
+
+		turn, err := caller.Converse(ctx, req)
+		if err == nil {
+			turn.FallbackFrom = failures
+			return turn, nil
+		}
+
+		if !fallsBack(err) {
+			return nil, err
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+
+		class := ClassOf(err)
+		failures = append(failures, fmt.Sprintf("%s: %s", entry.provider, class))
+		klog.Warningf("Provider %s failed (%s) during tool-use turn: %v", entry.provider, class, err)
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no provider in the fallback chain supports tool calling")
+	}
+	return nil, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+// fallsBack reports whether another provider may succeed where err occurred:
+// rate limits, exhausted quota, overload, outages and transport errors, and
+// rejected keys since every provider has its own
+func fallsBack(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch ClassOf(err) {
+	case ErrorClassRateLimited, ErrorClassQuota, ErrorClassOverloaded, ErrorClassUnavailable, ErrorClassAuth, ErrorClassUnknown:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// failingClient fails every request with err
+type failingClient struct {
+	err   error
+	calls int
+}
+
+func (c *failingClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	c.calls++
+	return nil, c.err
+}
+
+func apiError(class ErrorClass) error {
+	return &APIError{Provider: ProviderGemini, StatusCode: 500, Message: string(class), Class: class}
+}
+
+func TestFallbackClientAnalyze(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantFallback bool
+	}{
+		{"rate limited", apiError(ErrorClassRateLimited), true},
+		{"quota exceeded", apiError(ErrorClassQuota), true},
+		{"overloaded", apiError(ErrorClassOverloaded), true},
+		{"unavailable", apiError(ErrorClassUnavailable), true},
+		{"transport error", errors.New("connection reset by peer"), true},
+		{"invalid request", apiError(ErrorClassInvalidRequest), false},
+		{"auth", apiError(ErrorClassAuth), true},
+		{"content blocked", apiError(ErrorClassContentBlocked), false},
+		{"canceled", fmt.Errorf("request failed: %w", context.Canceled), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &failingClient{err: tt.err}
+			second := NewMockClient(Options{})
+			f, err := NewFallbackClient([]Provider{ProviderGemini, ProviderMock}, []Client{first, second})
+			if err != nil {
+				t.Fatalf("NewFallbackClient: %v", err)
+			}
+
+			analysis, err := f.Analyze(context.Background(), &Request{EventType: "OOMKilled"})
+			if first.calls != 1 {
+				t.Errorf("first provider called %d times, want 1", first.calls)
+			}
+			if !tt.wantFallback {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if analysis.Provider != ProviderMock {
+				t.Errorf("Provider = %q, want %q", analysis.Provider, ProviderMock)
+			}
+			if len(analysis.FallbackFrom) != 1 {
+				t.Errorf("FallbackFrom = %v, want one failure", analysis.FallbackFrom)
+			}
+		})
+	}
+}
+
+func TestFallbackClientAllFail(t *testing.T) {
+	first := &failingClient{err: apiError(ErrorClassRateLimited)}
+	second := &failingClient{err: apiError(ErrorClassUnavailable)}
+	f, err := NewFallbackClient([]Provider{ProviderGemini, ProviderClaude}, []Client{first, second})
+	if err != nil {
+		t.Fatalf("NewFallbackClient: %v", err)
+	}
+
+	_, err = f.Analyze(context.Background(), &Request{})
+	if !errors.Is(err, ErrRateLimited) || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want both provider errors joined", err)
+	}
+}
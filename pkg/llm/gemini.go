@@ -1,107 +1,351 @@
 package llm
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"strings"
+)
+
+const (
+	geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	geminiDefaultModel   = "gemini-2.5-flash"
 )
 
 // GeminiClient implements LLM client for Google Gemini
 type GeminiClient struct {
-	apiKey string
+	apiKey    string
+	model     string
+	maxTokens int
+	baseURL   string
+	transport *transport
 }
 
 // NewGeminiClient creates a new Gemini client
-func NewGeminiClient(apiKey string) *GeminiClient {
-	return &GeminiClient{
-		apiKey: apiKey,
+func NewGeminiClient(opts Options) *GeminiClient {
+	c := &GeminiClient{
+		apiKey:    opts.APIKey,
+		model:     opts.Model,
+		maxTokens: opts.MaxTokens,
+		baseURL:   strings.TrimSuffix(opts.BaseURL, "/"),
+		transport: newTransport(ProviderGemini, opts),
+	}
+	if c.model == "" {
+		c.model = geminiDefaultModel
+	}
+	if c.baseURL == "" {
+		c.baseURL = geminiDefaultBaseURL
 	}
+	return c
 }
 
 type geminiRequest struct {
-	Contents []geminiContent `json:"contents"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+	Tools             []geminiTool           `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig      `json:"toolConfig,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	ResponseMimeType string `json:"responseMimeType,omitempty"`
+	// MaxOutputTokens includes thinking tokens on thinking models; 0 uses the model default
+	MaxOutputTokens int `json:"maxOutputTokens,omitempty"`
 }
 
 type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
 	Parts []geminiPart `json:"parts"`
 }
 
 type geminiPart struct {
-	Text string `json:"text"`
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	// ThoughtSignature must be sent back unchanged with the part it came with
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode string `json:"mode"`
+	} `json:"functionCallingConfig"`
 }
 
 type geminiResponse struct {
 	Candidates []struct {
-		Content struct {
-			Parts []geminiPart `json:"parts"`
-		} `json:"content"`
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
 	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	} `json:"usageMetadata"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
 	Error *struct {
 		Message string `json:"message"`
 		Code    int    `json:"code"`
+		Status  string `json:"status"`
 	} `json:"error,omitempty"`
 }
 
 // Analyze performs root cause analysis using Gemini
-func (c *GeminiClient) Analyze(eventType, podName, namespace, logs string) (string, error) {
-	prompt := fmt.Sprintf(`You are a Kubernetes SRE expert. Analyze this pod incident and provide concise troubleshooting guidance.
-
-Event Type: %s
-Pod: %s/%s
-Context:
-%s
-
-Provide a brief analysis with:
-1. Root cause (1-2 sentences)
-2. Immediate fix (specific kubectl commands or config changes)
-3. Prevention (1 sentence)
-
-Keep the response under 300 words.`, eventType, namespace, podName, logs)
+func (c *GeminiClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	url := fmt.Sprintf("%s/models/%s:generateContent", c.baseURL, c.model)
 
-	// Gemini API endpoint - using gemini-2.5-flash model
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash:generateContent?key=%s", c.apiKey)
+	// Send the key as a header so it never appears in error messages containing the URL
+	headers := map[string]string{
+		"x-goog-api-key": c.apiKey,
+	}
 
 	reqBody := geminiRequest{
+		SystemInstruction: &geminiContent{
+			Parts: []geminiPart{{Text: req.systemPrompt()}},
+		},
+		GenerationConfig: geminiGenerationConfig{
+			ResponseMimeType: "application/json",
+			MaxOutputTokens:  c.maxTokens,
+		},
 		Contents: []geminiContent{
 			{
 				Parts: []geminiPart{
-					{Text: prompt},
+					{Text: req.Prompt},
 				},
 			},
 		},
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	body, err := c.transport.postJSON(ctx, url, headers, reqBody, decodeGeminiError)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to call Gemini API: %w", err)
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	// Check for blocked prompts or responses
+	if geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
+		return nil, contentBlockedError(ProviderGemini, geminiResp.PromptFeedback.BlockReason)
+	}
+	if len(geminiResp.Candidates) > 0 {
+		switch reason := geminiResp.Candidates[0].FinishReason; reason {
+		case "SAFETY", "PROHIBITED_CONTENT", "BLOCKLIST", "SPII", "RECITATION":
+			return nil, contentBlockedError(ProviderGemini, reason)
+		}
+	}
+
+	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no response from Gemini")
+	}
+
+	analysis := ParseAnalysis(geminiResp.Candidates[0].Content.Parts[0].Text)
+	analysis.Provider = ProviderGemini
+	analysis.Model = c.model
+	analysis.Usage = Usage{
+		Provider:     ProviderGemini,
+		Model:        c.model,
+		InputTokens:  geminiResp.UsageMetadata.PromptTokenCount,
+		OutputTokens: geminiResp.UsageMetadata.CandidatesTokenCount + geminiResp.UsageMetadata.ThoughtsTokenCount,
+	}
+	return analysis, nil
+}
+
+// Converse runs one turn of a tool-use conversation with Gemini
+func (c *GeminiClient) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	url := fmt.Sprintf("%s/models/%s:generateContent", c.baseURL, c.model)
+	headers := map[string]string{
+		"x-goog-api-key": c.apiKey,
+	}
+
+	// JSON response mode cannot be combined with function calling, so the
+	// final answer relies on the output instructions and ParseAnalysis instead
+	reqBody := geminiRequest{
+		SystemInstruction: &geminiContent{
+			Parts: []geminiPart{{Text: req.System}},
+		},
+		Contents:         geminiContents(req.Messages),
+		GenerationConfig: geminiGenerationConfig{MaxOutputTokens: c.maxTokens},
+	}
+	if len(req.Tools) > 0 {
+		var decls []geminiFunctionDeclaration
+		for _, tool := range req.Tools {
+			decls = append(decls, geminiFunctionDeclaration{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  geminiSchema(tool.Parameters),
+			})
+		}
+		reqBody.Tools = []geminiTool{{FunctionDeclarations: decls}}
+		if req.DisableTools {
+			reqBody.ToolConfig = &geminiToolConfig{}
+			reqBody.ToolConfig.FunctionCallingConfig.Mode = "NONE"
+		}
+	}
+
+	body, err := c.transport.postJSON(ctx, url, headers, reqBody, decodeGeminiError)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
 	var geminiResp geminiResponse
 	if err := json.Unmarshal(body, &geminiResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	// Check for API errors
-	if geminiResp.Error != nil {
-		return "", fmt.Errorf("Gemini API error: %s (code %d)", geminiResp.Error.Message, geminiResp.Error.Code)
+	if geminiResp.PromptFeedback != nil && geminiResp.PromptFeedback.BlockReason != "" {
+		return nil, contentBlockedError(ProviderGemini, geminiResp.PromptFeedback.BlockReason)
+	}
+	if len(geminiResp.Candidates) == 0 {
+		return nil, fmt.Errorf("no response from Gemini")
+	}
+	switch reason := geminiResp.Candidates[0].FinishReason; reason {
+	case "SAFETY", "PROHIBITED_CONTENT", "BLOCKLIST", "SPII", "RECITATION":
+		return nil, contentBlockedError(ProviderGemini, reason)
 	}
 
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no response from Gemini")
+	content := geminiResp.Candidates[0].Content
+	content.Role = "model"
+	msg := Message{Role: RoleAssistant, nativeProvider: ProviderGemini}
+	for i, part := range content.Parts {
+		if part.FunctionCall != nil {
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("%s-%d", part.FunctionCall.Name, i)
+			}
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: id, Name: part.FunctionCall.Name, Arguments: part.FunctionCall.Args})
+			continue
+		}
+		msg.Content += part.Text
+	}
+	if msg.Content == "" && len(msg.ToolCalls) == 0 {
+		return nil, fmt.Errorf("no response from Gemini")
 	}
+	if msg.native, err = json.Marshal(content); err != nil {
+		return nil, fmt.Errorf("failed to marshal response content: %w", err)
+	}
+
+	usage := geminiResp.UsageMetadata
+	return &Turn{
+		Message:  msg,
+		Provider: ProviderGemini,
+		Model:    c.model,
+		Usage: Usage{
+			InputTokens:  usage.PromptTokenCount,
+			OutputTokens: usage.CandidatesTokenCount + usage.ThoughtsTokenCount,
+		},
+	}, nil
+}
+
+// geminiContents converts the conversation, replaying Gemini's own model turns
+// verbatim and grouping consecutive tool results into one user turn
+func geminiContents(messages []Message) []geminiContent {
+	var out []geminiContent
+	for _, m := range messages {
+		switch m.Role {
+		case RoleTool:
+			key := "output"
+			if m.IsError {
+				key = "error"
+			}
+			part := geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     m.ToolName,
+				Response: map[string]interface{}{key: m.Content},
+			}}
+			if n := len(out); n > 0 && out[n-1].Role == "user" && out[n-1].Parts[0].FunctionResponse != nil {
+				out[n-1].Parts = append(out[n-1].Parts, part)
+				continue
+			}
+			out = append(out, geminiContent{Role: "user", Parts: []geminiPart{part}})
+		case RoleAssistant:
+			var content geminiContent
+			if m.nativeProvider == ProviderGemini && json.Unmarshal(m.native, &content) == nil {
+				out = append(out, content)
+				continue
+			}
+			content = geminiContent{Role: "model"}
+			if m.Content != "" {
+				content.Parts = append(content.Parts, geminiPart{Text: m.Content})
+			}
+			for _, call := range m.ToolCalls {
+				content.Parts = append(content.Parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: call.Name, Args: call.arguments()}})
+			}
+			out = append(out, content)
+		default:
+			out = append(out, geminiContent{Role: "user", Parts: []geminiPart{{Text: m.Content}}})
+		}
+	}
+	return out
+}
 
-	return geminiResp.Candidates[0].Content.Parts[0].Text, nil
+// geminiSchema converts a JSON schema to Gemini's OpenAPI subset, which spells
+// types in upper case
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		switch val := v.(type) {
+		case string:
+			if k == "type" {
+				val = strings.ToUpper(val)
+			}
+			out[k] = val
+		case map[string]interface{}:
+			if k == "properties" {
+				props := make(map[string]interface{}, len(val))
+				for name, prop := range val {
+					if p, ok := prop.(map[string]interface{}); ok {
+						props[name] = geminiSchema(p)
+					} else {
+						props[name] = prop
+					}
+				}
+				out[k] = props
+			} else {
+				out[k] = geminiSchema(val)
+			}
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func decodeGeminiError(statusCode int, body []byte) *APIError {
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil || geminiResp.Error == nil {
+		return nil
+	}
+	return &APIError{
+		Type:    geminiResp.Error.Status,
+		Message: geminiResp.Error.Message,
+	}
 }
@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGeminiClientAnalyze(t *testing.T) {
+	response := `{
+		"candidates": [{"content": {"parts": [{"text": "{\"rootCause\": \"liveness probe too strict\", \"severity\": \"low\"}"}]}, "finishReason": "STOP"}],
+		"usageMetadata": {"promptTokenCount": 300, "candidatesTokenCount": 40, "thoughtsTokenCount": 200}
+	}`
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		want      interface{}
+	}{
+		{"configured max tokens", 4000, float64(4000)},
+		{"model default", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, got := stubServer(t, http.StatusOK, response)
+			client := NewGeminiClient(Options{APIKey: "g-key", Model: "gemini-test", MaxTokens: tt.maxTokens, BaseURL: server.URL})
+
+			analysis, err := client.Analyze(context.Background(), &Request{Prompt: "probe failed"})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if got.Path != "/models/gemini-test:generateContent" {
+				t.Errorf("path = %q", got.Path)
+			}
+			if got.Header.Get("x-goog-api-key") != "g-key" {
+				t.Error("API key not sent in the x-goog-api-key header")
+			}
+			config, _ := got.Body["generationConfig"].(map[string]interface{})
+			if config["maxOutputTokens"] != tt.want {
+				t.Errorf("maxOutputTokens = %v, want %v", config["maxOutputTokens"], tt.want)
+			}
+
+			if analysis.RootCause != "liveness probe too strict" {
+				t.Errorf("RootCause = %q", analysis.RootCause)
+			}
+			// Thinking tokens are billed as output
+			if analysis.Usage.InputTokens != 300 || analysis.Usage.OutputTokens != 240 {
+				t.Errorf("usage = %+v, want 300 in, 240 out", analysis.Usage)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// mockDefaultKey selects the canned response for event types without their own
+const mockDefaultKey = "default"
+
+// mockResponses are the built-in canned outputs, used when neither fixtures
+// nor configured responses cover an event type
+var mockResponses = map[string]string{
+	"CrashLoopBackOff": `{"rootCause": "The container exits shortly after start (mock analysis).", "severity": "high", "confidence": 0.9, "immediateFix": ["Inspect the previous container logs for the exit reason"], "kubectlCommands": ["kubectl logs <pod> --previous"], "prevention": "Add a startup probe and validate configuration before rollout."}`,
+	"ImagePullBackOff": `{"rootCause": "The image cannot be pulled (mock analysis).", "severity": "medium", "confidence": 0.9, "immediateFix": ["Verify the image name, tag and pull secret"], "kubectlCommands": ["kubectl describe pod <pod>"], "prevention": "Pin images by digest and monitor registry credentials."}`,
+	"OOMKilled":        `{"rootCause": "The container exceeded its memory limit (mock analysis).", "severity": "high", "confidence": 0.9, "immediateFix": ["Raise the memory limit or reduce usage"], "kubectlCommands": ["kubectl top pod <pod>"], "prevention": "Size memory limits from observed peak usage."}`,
+	mockDefaultKey:     `{"rootCause": "Mock analysis for offline testing.", "severity": "low", "confidence": 0.5, "immediateFix": ["No action; this is a canned response"], "kubectlCommands": [], "prevention": "Not applicable."}`,
+}
+
+// MockClient returns deterministic canned analyses without calling any API.
+// Responses are looked up by event type in FixturesDir (<EventType>.json, then
+// default.json), then in Options.Responses, then in the built-in set; an event
+// type's own response from any source wins over a default one.
+type MockClient struct {
+	model       string
+	fixturesDir string
+	responses   map[string]string
+}
+
+// NewMockClient creates a mock client
+func NewMockClient(opts Options) *MockClient {
+	c := &MockClient{
+		model:       opts.Model,
+		fixturesDir: opts.FixturesDir,
+		responses:   opts.Responses,
+	}
+	if c.model == "" {
+		c.model = "mock"
+	}
+	return c
+}
+
+// Analyze returns the canned analysis for the request's event type
+func (c *MockClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	text, err := c.response(req.EventType)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := ParseAnalysis(text)
+	analysis.Provider = ProviderMock
+	analysis.Model = c.model
+	analysis.Usage = c.usage(req.systemPrompt()+req.Prompt, text)
+	return analysis, nil
+}
+
+// Converse answers immediately without calling tools, so agent mode works offline
+func (c *MockClient) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	input := req.System
+	for _, m := range req.Messages {
+		input += m.Content
+	}
+
+	text, err := c.response(req.EventType)
+	if err != nil {
+		return nil, err
+	}
+	return &Turn{
+		Message:  Message{Role: RoleAssistant, Content: text},
+		Usage:    c.usage(input, text),
+		Provider: ProviderMock,
+		Model:    c.model,
+	}, nil
+}
+
+func (c *MockClient) response(eventType string) (string, error) {
+	for _, key := range []string{eventType, mockDefaultKey} {
+		if key == "" {
+			continue
+		}
+		if c.fixturesDir != "" {
+			data, err := os.ReadFile(filepath.Join(c.fixturesDir, key+".json"))
+			if err == nil {
+				return string(data), nil
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("failed to read mock fixture: %w", err)
+			}
+		}
+		if text, ok := c.responses[key]; ok {
+			return text, nil
+		}
+		if text, ok := mockResponses[key]; ok {
+			return text, nil
+		}
+	}
+	return "", fmt.Errorf("no mock response for event type %q", eventType)
+}
+
+// usage estimates tokens so cost accounting can be exercised offline
+func (c *MockClient) usage(input, output string) Usage {
+	return Usage{
+		Provider:     ProviderMock,
+		Model:        c.model,
+		InputTokens:  EstimateTokens(ProviderMock, input),
+		OutputTokens: EstimateTokens(ProviderMock, output),
+	}
+}
@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/prompt"
+)
+
+func TestMockClientResponses(t *testing.T) {
+	fixtures := t.TempDir()
+	if err := os.WriteFile(filepath.Join(fixtures, "OOMKilled.json"), []byte(`{"rootCause": "from fixture"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      Options
+		eventType string
+		want      string
+	}{
+		{"built-in response", Options{}, "ImagePullBackOff", "The image cannot be pulled (mock analysis)."},
+		{"built-in default", Options{}, "FailedMount", "Mock analysis for offline testing."},
+		{"configured response", Options{Responses: map[string]string{"CrashLoopBackOff": `{"rootCause": "configured"}`}}, "CrashLoopBackOff", "configured"},
+		{"configured default", Options{Responses: map[string]string{"default": `{"rootCause": "configured default"}`}}, "FailedMount", "configured default"},
+		{"event type beats configured default", Options{Responses: map[string]string{"default": `{"rootCause": "configured default"}`}}, "OOMKilled", "The container exceeded its memory limit (mock analysis)."},
+		{"fixture", Options{FixturesDir: fixtures, Responses: map[string]string{"OOMKilled": `{"rootCause": "configured"}`}}, "OOMKilled", "from fixture"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockClient(tt.opts)
+			analysis, err := client.Analyze(context.Background(), &Request{Prompt: "incident", EventType: tt.eventType})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if analysis.RootCause != tt.want {
+				t.Errorf("RootCause = %q, want %q", analysis.RootCause, tt.want)
+			}
+			if analysis.Provider != ProviderMock || analysis.Usage.InputTokens == 0 {
+				t.Errorf("provider, usage = %s, %+v, want mock with estimated tokens", analysis.Provider, analysis.Usage)
+			}
+		})
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "recordings")
+	req := &Request{
+		System:    "You are an SRE.",
+		Prompt:    "Logs:\n" + prompt.Fence("panic: nil map"),
+		EventType: "CrashLoopBackOff",
+	}
+
+	recorder, err := NewRecordingClient(NewMockClient(Options{}), dir)
+	if err != nil {
+		t.Fatalf("NewRecordingClient: %v", err)
+	}
+	recorded, err := recorder.Analyze(ctx, req)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 || !strings.HasPrefix(entries[0].Name(), "CrashLoopBackOff-") {
+		t.Errorf("recordings = %v, want one named after the event type", entries)
+	}
+
+	// The prompt is rendered again in a later run, fences included
+	again := *req
+	again.Prompt = "Logs:\n" + prompt.Fence("panic: nil map")
+	replayed, err := NewReplayClient(dir).Analyze(ctx, &again)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if replayed.RootCause != recorded.RootCause {
+		t.Errorf("replayed RootCause = %q, want %q", replayed.RootCause, recorded.RootCause)
+	}
+	if usage := replayed.TotalUsage(); usage.InputTokens != 0 || usage.OutputTokens != 0 || usage.CostUSD != 0 {
+		t.Errorf("replayed usage = %+v, want none charged", usage)
+	}
+
+	changed := *req
+	changed.Prompt += "\nmore context"
+	if _, err := NewReplayClient(dir).Analyze(ctx, &changed); err == nil {
+		t.Error("replay of an unrecorded request succeeded")
+	}
+}
+
+func TestRecordingClientConverse(t *testing.T) {
+	recorder, err := NewRecordingClient(NewMockClient(Options{}), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewRecordingClient: %v", err)
+	}
+	agent, err := NewAgent(recorder, []Tool{{Name: "get_pod_events"}}, nil, AgentOptions{})
+	if err != nil {
+		t.Fatalf("NewAgent over a recording client: %v", err)
+	}
+	if _, err := agent.Analyze(context.Background(), &Request{Prompt: "pod stuck", EventType: "OOMKilled"}); err != nil {
+		t.Errorf("Analyze: %v", err)
+	}
+
+	plain, err := NewRecordingClient(&failingClient{}, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewRecordingClient: %v", err)
+	}
+	if _, err := plain.Converse(context.Background(), &ConverseRequest{}); err == nil {
+		t.Error("Converse succeeded over a client without tool calling")
+	}
+}
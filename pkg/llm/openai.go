@@ -1,35 +1,296 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 )
 
-// OpenAIClient implements LLM client for OpenAI
+const (
+	openAIDefaultBaseURL   = "https://api.openai.com/v1"
+	openAIDefaultModel     = "gpt-4o"
+	openAIDefaultMaxTokens = 1024
+)
+
+// OpenAIClient implements LLM client for OpenAI and OpenAI-compatible servers
 type OpenAIClient struct {
-	apiKey string
+	provider     Provider
+	apiKey       string
+	authHeader   string
+	model        string
+	maxTokens    int
+	baseURL      string
+	organization string
+	project      string
+	transport    *transport
 }
 
 // NewOpenAIClient creates a new OpenAI client
-func NewOpenAIClient(apiKey string) *OpenAIClient {
-	return &OpenAIClient{
-		apiKey: apiKey,
+func NewOpenAIClient(opts Options) *OpenAIClient {
+	c := &OpenAIClient{
+		provider:     ProviderOpenAI,
+		apiKey:       opts.APIKey,
+		model:        opts.Model,
+		maxTokens:    opts.MaxTokens,
+		baseURL:      strings.TrimSuffix(opts.BaseURL, "/"),
+		organization: opts.Organization,
+		project:      opts.Project,
+		transport:    newTransport(ProviderOpenAI, opts),
+	}
+	if c.model == "" {
+		c.model = openAIDefaultModel
+	}
+	if c.maxTokens <= 0 {
+		c.maxTokens = openAIDefaultMaxTokens
+	}
+	if c.baseURL == "" {
+		c.baseURL = openAIDefaultBaseURL
+	}
+	return c
+}
+
+// NewOpenAICompatibleClient creates a client for a self-hosted server exposing the
+// OpenAI Chat Completions API (vLLM, Ollama, LM Studio, ...)
+func NewOpenAICompatibleClient(opts Options) (*OpenAIClient, error) {
+	if opts.BaseURL == "" {
+		return nil, fmt.Errorf("%s provider requires a base URL", ProviderOpenAICompatible)
+	}
+	if opts.Model == "" {
+		return nil, fmt.Errorf("%s provider requires a model", ProviderOpenAICompatible)
 	}
+
+	c := NewOpenAIClient(opts)
+	c.provider = ProviderOpenAICompatible
+	c.authHeader = opts.AuthHeader
+	c.transport = newTransport(ProviderOpenAICompatible, opts)
+	return c, nil
+}
+
+type openAIRequest struct {
+	Model     string          `json:"model"`
+	MaxTokens int             `json:"max_tokens"`
+	Messages  []openAIMessage `json:"messages"`
+
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// openAIToolRequest is a Chat Completions request with function tools
+type openAIToolRequest struct {
+	Model      string          `json:"model"`
+	MaxTokens  int             `json:"max_tokens"`
+	Messages   []openAIMessage `json:"messages"`
+	Tools      []openAITool    `json:"tools,omitempty"`
+	ToolChoice string          `json:"tool_choice,omitempty"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name string `json:"name"`
+		// Arguments is a JSON object encoded as a string
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIErrorResponse struct {
+	Error *struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+	} `json:"error,omitempty"`
 }
 
 // Analyze performs root cause analysis using OpenAI
-func (c *OpenAIClient) Analyze(eventType, podName, namespace, logs string) (string, error) {
-	// TODO: Implement actual OpenAI API call
-	prompt := fmt.Sprintf(`Analyze this Kubernetes incident:
+func (c *OpenAIClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	reqBody := openAIRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		Messages: []openAIMessage{
+			{Role: "system", Content: req.systemPrompt()},
+			{Role: "user", Content: req.Prompt},
+		},
+	}
+	// JSON mode is not universally supported by self-hosted servers
+	if c.provider == ProviderOpenAI {
+		reqBody.ResponseFormat = &openAIResponseFormat{Type: "json_object"}
+	}
 
-Event Type: %s
-Pod: %s/%s
-Logs:
-%s
+	body, err := c.transport.postJSON(ctx, c.baseURL+"/chat/completions", c.headers(), reqBody, decodeOpenAIError)
+	if err != nil {
+		return nil, err
+	}
 
-Provide:
-1. Root cause
-2. Immediate fix
-3. Long-term solution`, eventType, namespace, podName, logs)
+	var openAIResp openAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
 
-	return fmt.Sprintf("Analysis for %s:\n\nRoot Cause: [Implement OpenAI API]\nFix: [Pending implementation]\n\nPrompt: %s", eventType, prompt), nil
+	if len(openAIResp.Choices) > 0 && openAIResp.Choices[0].FinishReason == "content_filter" {
+		return nil, contentBlockedError(c.provider, openAIResp.Choices[0].FinishReason)
+	}
+	if len(openAIResp.Choices) == 0 || openAIResp.Choices[0].Message.Content == "" {
+		return nil, fmt.Errorf("no response from %s", c.provider)
+	}
+
+	analysis := ParseAnalysis(openAIResp.Choices[0].Message.Content)
+	analysis.Provider = c.provider
+	analysis.Model = c.model
+	analysis.Usage = Usage{
+		Provider:     c.provider,
+		Model:        c.model,
+		InputTokens:  openAIResp.Usage.PromptTokens,
+		OutputTokens: openAIResp.Usage.CompletionTokens,
+	}
+	return analysis, nil
+}
+
+// Converse runs one turn of a tool-use conversation over Chat Completions
+func (c *OpenAIClient) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	reqBody := openAIToolRequest{
+		Model:     c.model,
+		MaxTokens: c.maxTokens,
+		Messages:  append([]openAIMessage{{Role: "system", Content: req.System}}, openAIMessages(req.Messages)...),
+	}
+	for _, tool := range req.Tools {
+		reqBody.Tools = append(reqBody.Tools, openAITool{
+			Type: "function",
+			Function: openAIFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	if req.DisableTools && len(reqBody.Tools) > 0 {
+		reqBody.ToolChoice = "none"
+	}
+
+	body, err := c.transport.postJSON(ctx, c.baseURL+"/chat/completions", c.headers(), reqBody, decodeOpenAIError)
+	if err != nil {
+		return nil, err
+	}
+
+	var openAIResp openAIResponse
+	if err := json.Unmarshal(body, &openAIResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if len(openAIResp.Choices) > 0 && openAIResp.Choices[0].FinishReason == "content_filter" {
+		return nil, contentBlockedError(c.provider, openAIResp.Choices[0].FinishReason)
+	}
+	if len(openAIResp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from %s", c.provider)
+	}
+
+	choice := openAIResp.Choices[0].Message
+	msg := Message{Role: RoleAssistant, Content: choice.Content}
+	for _, call := range choice.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: json.RawMessage(call.Function.Arguments),
+		})
+	}
+	if msg.Content == "" && len(msg.ToolCalls) == 0 {
+		return nil, fmt.Errorf("no response from %s", c.provider)
+	}
+
+	return &Turn{
+		Message:  msg,
+		Provider: c.provider,
+		Model:    c.model,
+		Usage: Usage{
+			InputTokens:  openAIResp.Usage.PromptTokens,
+			OutputTokens: openAIResp.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+func openAIMessages(messages []Message) []openAIMessage {
+	out := make([]openAIMessage, 0, len(messages))
+	for _, m := range messages {
+		switch m.Role {
+		case RoleTool:
+			out = append(out, openAIMessage{Role: "tool", Content: m.Content, ToolCallID: m.ToolCallID})
+		case RoleAssistant:
+			msg := openAIMessage{Role: "assistant", Content: m.Content}
+			for _, call := range m.ToolCalls {
+				tc := openAIToolCall{ID: call.ID, Type: "function"}
+				tc.Function.Name = call.Name
+				tc.Function.Arguments = string(call.arguments())
+				msg.ToolCalls = append(msg.ToolCalls, tc)
+			}
+			out = append(out, msg)
+		default:
+			out = append(out, openAIMessage{Role: "user", Content: m.Content})
+		}
+	}
+	return out
+}
+
+func (c *OpenAIClient) headers() map[string]string {
+	headers := map[string]string{}
+	if c.authHeader != "" {
+		headers[c.authHeader] = c.apiKey
+	} else if c.apiKey != "" {
+		headers["Authorization"] = "Bearer " + c.apiKey
+	}
+	if c.organization != "" {
+		headers["OpenAI-Organization"] = c.organization
+	}
+	if c.project != "" {
+		headers["OpenAI-Project"] = c.project
+	}
+	return headers
+}
+
+func decodeOpenAIError(statusCode int, body []byte) *APIError {
+	var errResp openAIErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error == nil {
+		return nil
+	}
+
+	errType := errResp.Error.Type
+	if code := strings.Trim(string(errResp.Error.Code), `"`); code != "" && code != "null" {
+		errType = code
+	}
+	return &APIError{
+		Type:    errType,
+		Message: errResp.Error.Message,
+	}
 }
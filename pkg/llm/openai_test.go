@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+const openAIAnalysisResponse = `{
+	"choices": [{"message": {"role": "assistant", "content": "{\"rootCause\": \"image tag does not exist\", \"severity\": \"medium\", \"confidence\": 0.9}"}, "finish_reason": "stop"}],
+	"usage": {"prompt_tokens": 200, "completion_tokens": 60}
+}`
+
+func TestOpenAIClientAnalyze(t *testing.T) {
+	server, got := stubServer(t, http.StatusOK, openAIAnalysisResponse)
+
+	client := NewOpenAIClient(Options{APIKey: "sk-test", Model: "gpt-test", MaxTokens: 700, BaseURL: server.URL, Organization: "org-1", Project: "proj-1"})
+	analysis, err := client.Analyze(context.Background(), &Request{Prompt: "image pull failed"})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	if got.Path != "/chat/completions" {
+		t.Errorf("path = %q, want /chat/completions", got.Path)
+	}
+	headers := map[string]string{
+		"Authorization":       "Bearer sk-test",
+		"OpenAI-Organization": "org-1",
+		"OpenAI-Project":      "proj-1",
+	}
+	for name, want := range headers {
+		if v := got.Header.Get(name); v != want {
+			t.Errorf("header %s = %q, want %q", name, v, want)
+		}
+	}
+	if got.Body["model"] != "gpt-test" || got.Body["max_tokens"] != float64(700) {
+		t.Errorf("model, max_tokens = %v, %v", got.Body["model"], got.Body["max_tokens"])
+	}
+	if format, _ := got.Body["response_format"].(map[string]interface{}); format["type"] != "json_object" {
+		t.Errorf("response_format = %v, want json_object", got.Body["response_format"])
+	}
+	if messages, _ := got.Body["messages"].([]interface{}); len(messages) != 2 {
+		t.Errorf("messages = %v, want system and user", got.Body["messages"])
+	}
+
+	if analysis.RootCause != "image tag does not exist" || analysis.Severity != SeverityMedium {
+		t.Errorf("analysis = %+v", analysis)
+	}
+	if analysis.Usage.InputTokens != 200 || analysis.Usage.OutputTokens != 60 {
+		t.Errorf("usage = %+v, want 200 in, 60 out", analysis.Usage)
+	}
+}
+
+func TestOpenAIClientErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     error
+	}{
+		{"rate limited", http.StatusTooManyRequests, `{"error": {"message": "Rate limit reached", "type": "requests", "code": "rate_limit_exceeded"}}`, ErrRateLimited},
+		{"quota", http.StatusTooManyRequests, `{"error": {"message": "You exceeded your current quota", "type": "insufficient_quota", "code": "insufficient_quota"}}`, ErrQuotaExceeded},
+		{"auth", http.StatusUnauthorized, `{"error": {"message": "Incorrect API key", "type": "invalid_request_error", "code": "invalid_api_key"}}`, ErrAuth},
+		{"content filter", http.StatusOK, `{"choices": [{"message": {"role": "assistant", "content": ""}, "finish_reason": "content_filter"}]}`, ErrContentBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := stubServer(t, tt.status, tt.response)
+			client := NewOpenAIClient(Options{APIKey: "sk-test", BaseURL: server.URL, MaxRetries: -1})
+
+			_, err := client.Analyze(context.Background(), &Request{Prompt: "image pull failed"})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOpenAICompatibleClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"base URL and model", Options{BaseURL: "http://vllm:8000/v1", Model: "llama-3.1-8b"}, false},
+		{"missing base URL", Options{Model: "llama-3.1-8b"}, true},
+		{"missing model", Options{BaseURL: "http://vllm:8000/v1"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewOpenAICompatibleClient(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleClientAnalyze(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKey     string
+		authHeader string
+		wantHeader string
+		wantValue  string
+	}{
+		{"no key", "", "", "Authorization", ""},
+		{"bearer key", "local-key", "", "Authorization", "Bearer local-key"},
+		{"custom header", "local-key", "X-Api-Key", "X-Api-Key", "local-key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, got := stubServer(t, http.StatusOK, openAIAnalysisResponse)
+			client, err := NewOpenAICompatibleClient(Options{APIKey: tt.apiKey, AuthHeader: tt.authHeader, BaseURL: server.URL + "/v1", Model: "qwen2.5"})
+			if err != nil {
+				t.Fatalf("NewOpenAICompatibleClient: %v", err)
+			}
+
+			analysis, err := client.Analyze(context.Background(), &Request{Prompt: "image pull failed"})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if got.Path != "/v1/chat/completions" {
+				t.Errorf("path = %q, want /v1/chat/completions", got.Path)
+			}
+			if v := got.Header.Get(tt.wantHeader); v != tt.wantValue {
+				t.Errorf("header %s = %q, want %q", tt.wantHeader, v, tt.wantValue)
+			}
+			if _, ok := got.Body["response_format"]; ok {
+				t.Error("response_format sent to a self-hosted server")
+			}
+			if analysis.Provider != ProviderOpenAICompatible {
+				t.Errorf("Provider = %q, want %q", analysis.Provider, ProviderOpenAICompatible)
+			}
+		})
+	}
+}
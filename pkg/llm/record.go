@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// RecordModeRecord saves every request/response pair of a real provider
+	RecordModeRecord = "record"
+	// RecordModeReplay answers from saved pairs without calling any provider
+	RecordModeReplay = "replay"
+)
+
+// recording is one saved request/response pair
+type recording struct {
+	EventType string    `json:"eventType,omitempty"`
+	System    string    `json:"system"`
+	Prompt    string    `json:"prompt"`
+	Analysis  *Analysis `json:"analysis"`
+}
+
+// RecordingClient wraps a client and saves each request with its analysis to
+// dir, named by a hash of the request, for ReplayClient to serve later
+type RecordingClient struct {
+	client Client
+	dir    string
+}
+
+// NewRecordingClient creates a client that records the responses of client
+func NewRecordingClient(client Client, dir string) (*RecordingClient, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	return &RecordingClient{client: client, dir: dir}, nil
+}
+
+// Analyze calls the wrapped client and saves the result
+func (c *RecordingClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	analysis, err := c.client.Analyze(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(recording{
+		EventType: req.EventType,
+		System:    req.System,
+		Prompt:    req.Prompt,
+		Analysis:  analysis,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recording: %w", err)
+	}
+
+	path := recordingPath(c.dir, req)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		klog.Warningf("Failed to save recording %s: %v", path, err)
+	} else {
+		klog.Infof("Recorded response to %s", path)
+	}
+	return analysis, nil
+}
+
+// Converse delegates to the wrapped client so agent mode keeps its tools while
+// recording; only the final Analyze requests are saved
+func (c *RecordingClient) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	caller, ok := c.client.(ToolCaller)
+	if !ok {
+		return nil, fmt.Errorf("client %T does not support tool calling", c.client)
+	}
+	return caller.Converse(ctx, req)
+}
+
+// ReplayClient serves analyses saved by RecordingClient. A request that was not
+// recorded is an error rather than a silent call to a real provider.
+type ReplayClient struct {
+	dir string
+}
+
+// NewReplayClient creates a client replaying the recordings in dir
+func NewReplayClient(dir string) *ReplayClient {
+	return &ReplayClient{dir: dir}
+}
+
+// Analyze returns the recorded analysis for an identical request
+func (c *ReplayClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	path := recordingPath(c.dir, req)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no recording for this request (expected %s)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	var rec recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", path, err)
+	}
+	if rec.Analysis == nil {
+		return nil, fmt.Errorf("recording %s has no analysis", path)
+	}
+	// A replay spends no tokens; the recorded usage would charge the budget
+	rec.Analysis.ClearUsage()
+	return rec.Analysis, nil
+}
+
+// recordingPath names a recording after the event type and a hash of the
+// prompts, so any change to the rendered request misses the recording
+func recordingPath(dir string, req *Request) string {
+	sum := sha256.Sum256([]byte(req.System + "\x00" + req.Prompt))
+	name := hex.EncodeToString(sum[:8]) + ".json"
+	if req.EventType != "" {
+		name = req.EventType + "-" + name
+	}
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+const defaultConfidenceThreshold = 0.7
+
+// TieredClient asks a fast triage model first and resends the same request to
+// a stronger model when the triage is unsure or the incident looks severe
+type TieredClient struct {
+	triage     Client
+	escalation Client
+	threshold  float64
+	severities map[Severity]bool
+}
+
+// NewTieredClient creates a tiered client. Triage results with a confidence
+// below threshold, with one of the given severities, or without structured
+// output are escalated.
+func NewTieredClient(triage, escalation Client, threshold float64, severities []Severity) *TieredClient {
+	if threshold <= 0 {
+		threshold = defaultConfidenceThreshold
+	}
+	t := &TieredClient{
+		triage:     triage,
+		escalation: escalation,
+		threshold:  threshold,
+		severities: map[Severity]bool{},
+	}
+	for _, s := range severities {
+		t.severities[s] = true
+	}
+	return t
+}
+
+// Analyze runs the triage model and escalates when needed. An escalated
+// analysis keeps the triage result in Analysis.Triage for comparison.
+func (t *TieredClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	triage, err := t.triage.Analyze(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		klog.Warningf("Triage model failed (%s), escalating: %v", ClassOf(err), err)
+		return t.escalation.Analyze(ctx, req)
+	}
+
+	reason := t.escalationReason(triage)
+	if reason == "" {
+		klog.Infof("Triage by %s is conclusive (severity %s, confidence %.2f)", triage.Model, triage.Severity, triage.Confidence)
+		return triage, nil
+	}
+
+	klog.Infof("Escalating triage by %s: %s", triage.Model, reason)
+	analysis, err := t.escalation.Analyze(ctx, req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		klog.Warningf("Escalation failed (%s), keeping the triage result: %v", ClassOf(err), err)
+		return triage, nil
+	}
+
+	analysis.Triage = triage
+	analysis.EscalationReason = reason
+	return analysis, nil
+}
+
+// Converse delegates to the escalation client; tool-use conversations are not tiered
+func (t *TieredClient) Converse(ctx context.Context, req *ConverseRequest) (*Turn, error) {
+	caller, ok := t.escalation.(ToolCaller)
+	if !ok {
+		return nil, fmt.Errorf("client %T does not support tool calling", t.escalation)
+	}
+	return caller.Converse(ctx, req)
+}
+
+func (t *TieredClient) escalationReason(a *Analysis) string {
+	switch {
+	case !a.Structured:
+		return "triage output was not structured"
+	case t.severities[a.Severity]:
+		return fmt.Sprintf("severity %s", a.Severity)
+	case a.Confidence < t.threshold:
+		return fmt.Sprintf("confidence %.2f below %.2f", a.Confidence, t.threshold)
+	}
+	return ""
+}
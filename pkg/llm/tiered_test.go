@@ -0,0 +1,92 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+// staticClient answers every request with a copy of text parsed as an analysis
+type staticClient struct {
+	text  string
+	err   error
+	calls int
+}
+
+func (c *staticClient) Analyze(ctx context.Context, req *Request) (*Analysis, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return ParseAnalysis(c.text), nil
+}
+
+func TestTieredClientAnalyze(t *testing.T) {
+	const strong = `{"rootCause": "strong model answer", "severity": "high", "confidence": 0.9}`
+
+	tests := []struct {
+		name          string
+		triage        *staticClient
+		escalation    *staticClient
+		wantRootCause string
+		wantReason    string
+	}{
+		{
+			name:          "confident triage is kept",
+			triage:        &staticClient{text: `{"rootCause": "triage answer", "severity": "low", "confidence": 0.9}`},
+			escalation:    &staticClient{text: strong},
+			wantRootCause: "triage answer",
+		},
+		{
+			name:          "low confidence escalates",
+			triage:        &staticClient{text: `{"rootCause": "triage answer", "severity": "low", "confidence": 0.4}`},
+			escalation:    &staticClient{text: strong},
+			wantRootCause: "strong model answer",
+			wantReason:    "confidence 0.40 below 0.70",
+		},
+		{
+			name:          "severe incident escalates",
+			triage:        &staticClient{text: `{"rootCause": "triage answer", "severity": "critical", "confidence": 0.95}`},
+			escalation:    &staticClient{text: strong},
+			wantRootCause: "strong model answer",
+			wantReason:    "severity critical",
+		},
+		{
+			name:          "unstructured triage escalates",
+			triage:        &staticClient{text: "I am not sure."},
+			escalation:    &staticClient{text: strong},
+			wantRootCause: "strong model answer",
+			wantReason:    "triage output was not structured",
+		},
+		{
+			name:          "failed triage escalates",
+			triage:        &staticClient{err: apiError(ErrorClassUnavailable)},
+			escalation:    &staticClient{text: strong},
+			wantRootCause: "strong model answer",
+		},
+		{
+			name:          "failed escalation keeps the triage",
+			triage:        &staticClient{text: `{"rootCause": "triage answer", "severity": "low", "confidence": 0.4}`},
+			escalation:    &staticClient{err: apiError(ErrorClassRateLimited)},
+			wantRootCause: "triage answer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewTieredClient(tt.triage, tt.escalation, 0, []Severity{SeverityCritical})
+			analysis, err := client.Analyze(context.Background(), &Request{Prompt: "incident"})
+			if err != nil {
+				t.Fatalf("Analyze: %v", err)
+			}
+			if analysis.RootCause != tt.wantRootCause {
+				t.Errorf("RootCause = %q, want %q", analysis.RootCause, tt.wantRootCause)
+			}
+			if analysis.EscalationReason != tt.wantReason {
+				t.Errorf("EscalationReason = %q, want %q", analysis.EscalationReason, tt.wantReason)
+			}
+			if tt.wantReason != "" && analysis.Triage == nil {
+				t.Error("Triage not kept on the escalated analysis")
+			}
+		})
+	}
+}
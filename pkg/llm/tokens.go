@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// charsPerToken is the average number of ASCII characters per token for each
+// provider's tokenizer on English text mixed with logs
+var charsPerToken = map[Provider]float64{
+	ProviderGemini:           4.0,
+	ProviderClaude:           3.5,
+	ProviderOpenAI:           4.0,
+	ProviderOpenAICompatible: 3.5,
+}
+
+// EstimateTokens approximates how many tokens text consumes with provider's
+// tokenizer. It errs on the high side: symbols common in logs and stack traces
+// and non-ASCII characters are counted as separate tokens.
+func EstimateTokens(provider Provider, text string) int {
+	if text == "" {
+		return 0
+	}
+
+	ratio, ok := charsPerToken[provider]
+	if !ok {
+		ratio = 3.5
+	}
+
+	var plain, extra int
+	for _, r := range text {
+		switch {
+		case r >= utf8.RuneSelf:
+			extra++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' ':
+			plain++
+		default:
+			// Punctuation, brackets and newlines rarely merge with neighbours
+			extra++
+		}
+	}
+
+	return int(float64(plain)/ratio+0.5) + extra/2 + 1
+}
+
+// TokenEstimator returns EstimateTokens bound to provider
+func TokenEstimator(provider Provider) func(string) int {
+	return func(text string) int {
+		return EstimateTokens(provider, text)
+	}
+}
@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		text     string
+		min, max int
+	}{
+		{"empty", ProviderClaude, "", 0, 0},
+		{"english", ProviderOpenAI, "the quick brown fox jumps over the lazy dog", 9, 13},
+		{"log line symbols", ProviderClaude, `{"level":"error","msg":"dial tcp: i/o timeout"}`, 15, 30},
+		{"non-ASCII", ProviderGemini, strings.Repeat("日本", 10), 10, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.provider, tt.text); got < tt.min || got > tt.max {
+				t.Errorf("EstimateTokens(%q) = %d, want %d to %d", tt.text, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestEstimateTokensByProvider(t *testing.T) {
+	text := strings.Repeat("connection refused by upstream service ", 50)
+	if claude, openai := EstimateTokens(ProviderClaude, text), EstimateTokens(ProviderOpenAI, text); claude <= openai {
+		t.Errorf("Claude estimate %d not above OpenAI estimate %d for a denser tokenizer", claude, openai)
+	}
+	if got, want := EstimateTokens("unknown", text), EstimateTokens(ProviderClaude, text); got != want {
+		t.Errorf("unknown provider estimate = %d, want the conservative default %d", got, want)
+	}
+}
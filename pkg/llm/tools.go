@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Tool describes a function the model may call during an agentic analysis
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments object
+	Parameters map[string]interface{}
+}
+
+// ToolCall is a request from the model to run a tool
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments json.RawMessage
+}
+
+// arguments returns the call's arguments, defaulting to an empty object
+func (c ToolCall) arguments() json.RawMessage {
+	if len(c.Arguments) == 0 {
+		return json.RawMessage("{}")
+	}
+	return c.Arguments
+}
+
+// Role identifies the author of a conversation message
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	// RoleTool carries the result of a tool call back to the model
+	RoleTool Role = "tool"
+)
+
+// Message is one provider-neutral conversation entry
+type Message struct {
+	Role    Role
+	Content string
+	// ToolCalls are set on assistant messages that requested tools
+	ToolCalls []ToolCall
+	// ToolCallID and ToolName identify the call a tool message answers
+	ToolCallID string
+	ToolName   string
+	IsError    bool
+
+	// native is the provider's own encoding of an assistant message, replayed
+	// verbatim to the same provider (e.g. Gemini thought signatures)
+	nativeProvider Provider
+	native         json.RawMessage
+}
+
+// ConverseRequest is one turn of a tool-use conversation
+type ConverseRequest struct {
+	System   string
+	Messages []Message
+	Tools    []Tool
+	// DisableTools forces a final text answer while keeping tools declared,
+	// which providers require once the history contains tool calls
+	DisableTools bool
+	// EventType is copied from Request for offline providers; it is not sent
+	EventType string
+}
+
+// Turn is the model's reply to a ConverseRequest
+type Turn struct {
+	// Message is the assistant message to append to the conversation
+	Message  Message
+	Usage    Usage
+	Provider Provider
+	Model    string
+	// FallbackFrom lists providers that failed before Provider answered
+	FallbackFrom []string
+}
+
+// ToolCaller is implemented by clients whose provider supports function calling
+type ToolCaller interface {
+	Converse(ctx context.Context, req *ConverseRequest) (*Turn, error)
+}
@@ -0,0 +1,200 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	defaultRequestTimeout = 60 * time.Second
+	defaultMaxRetries     = 3
+	defaultBaseBackoff    = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+)
+
+// errorDecoder extracts the provider error payload from a non-2xx response body
+type errorDecoder func(statusCode int, body []byte) *APIError
+
+// transport sends JSON requests to provider APIs with per-request timeouts and
+// exponential backoff on retryable failures. It is shared by all providers.
+type transport struct {
+	provider    Provider
+	httpClient  *http.Client
+	timeout     time.Duration
+	maxRetries  int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+func newTransport(provider Provider, opts Options) *transport {
+	t := &transport{
+		provider:    provider,
+		httpClient:  opts.HTTPClient,
+		timeout:     opts.Timeout,
+		maxRetries:  opts.MaxRetries,
+		baseBackoff: defaultBaseBackoff,
+		maxBackoff:  defaultMaxBackoff,
+	}
+	if t.httpClient == nil {
+		t.httpClient = http.DefaultClient
+	}
+	if t.timeout <= 0 {
+		t.timeout = defaultRequestTimeout
+	}
+	if t.maxRetries < 0 {
+		t.maxRetries = 0
+	} else if t.maxRetries == 0 {
+		t.maxRetries = defaultMaxRetries
+	}
+	return t
+}
+
+// postJSON sends payload to url and returns the body of a successful response.
+// Provider error payloads are decoded by decodeError and returned as *APIError.
+func (t *transport) postJSON(ctx context.Context, url string, headers map[string]string, payload interface{}, decodeError errorDecoder) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		body, retryAfter, err := t.do(ctx, url, headers, jsonData, decodeError)
+		if err == nil {
+			return body, nil
+		}
+
+		if !t.shouldRetry(ctx, err) || attempt >= t.maxRetries {
+			return nil, err
+		}
+
+		// The provider's Retry-After is honoured as long as the caller is
+		// willing to wait; without a deadline, up to maxBackoff
+		delay := t.backoff(attempt, retryAfter)
+		if deadline, ok := ctx.Deadline(); ok {
+			if time.Until(deadline) < delay {
+				// Not enough time left to wait for the provider
+				return nil, err
+			}
+		} else if retryAfter > t.maxBackoff {
+			return nil, err
+		}
+
+		klog.Warningf("%s request failed (attempt %d/%d), retrying in %v: %v", t.provider, attempt+1, t.maxRetries+1, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%s request cancelled: %w", t.provider, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// do performs a single attempt bounded by the per-request timeout
+func (t *transport) do(ctx context.Context, url string, headers map[string]string, jsonData []byte, decodeError errorDecoder) ([]byte, time.Duration, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to call %s API: %w", t.provider, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return body, 0, nil
+	}
+
+	apiErr := decodeError(resp.StatusCode, body)
+	if apiErr == nil {
+		apiErr = &APIError{Message: strings.TrimSpace(string(body))}
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	apiErr.Provider = t.provider
+	apiErr.StatusCode = resp.StatusCode
+	if apiErr.Class == "" {
+		apiErr.Class = classify(resp.StatusCode, apiErr.Type, apiErr.Message)
+	}
+	apiErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+
+	return nil, apiErr.RetryAfter, apiErr
+}
+
+// shouldRetry reports whether err is transient and the caller is still waiting
+func (t *transport) shouldRetry(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
+	// Network errors and per-request timeouts are worth another attempt
+	return true
+}
+
+// backoff returns the delay before the next attempt: the provider's Retry-After
+// when given, otherwise exponential backoff jittered between half the base
+// delay and a ceiling capped at maxBackoff
+func (t *transport) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	ceiling := t.baseBackoff << attempt
+	if ceiling <= 0 || ceiling > t.maxBackoff {
+		ceiling = t.maxBackoff
+	}
+	floor := t.baseBackoff / 2
+	if floor >= ceiling {
+		return ceiling
+	}
+	return floor + time.Duration(rand.Int63n(int64(ceiling-floor)))
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header (seconds or HTTP date)
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransportRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		deadline   time.Duration
+		wantCalls  int32
+		wantErr    error
+	}{
+		{"short delay is honoured", "1", 10 * time.Second, 2, nil},
+		{"delay past the deadline stops retrying", "60", 5 * time.Second, 1, ErrRateLimited},
+		{"delay above maxBackoff without a deadline stops retrying", "60", 0, 1, ErrRateLimited},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					w.Header().Set("Retry-After", tt.retryAfter)
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			tr := newTransport(ProviderOpenAI, Options{})
+			noDecoder := func(int, []byte) *APIError { return nil }
+			_, err := tr.postJSON(ctx, server.URL, nil, struct{}{}, noDecoder)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTransportBackoff(t *testing.T) {
+	tr := newTransport(ProviderOpenAI, Options{})
+
+	if got := tr.backoff(0, 45*time.Second); got != 45*time.Second {
+		t.Errorf("backoff with Retry-After 45s = %v, want it unchanged", got)
+	}
+	for attempt := 0; attempt < 64; attempt++ {
+		for i := 0; i < 100; i++ {
+			if got := tr.backoff(attempt, 0); got < tr.baseBackoff/2 || got > tr.maxBackoff {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", attempt, got, tr.baseBackoff/2, tr.maxBackoff)
+			}
+		}
+	}
+
+	// A cap below half the base delay still bounds the wait
+	tiny := newTransport(ProviderOpenAI, Options{})
+	tiny.maxBackoff = tiny.baseBackoff / 4
+	if got := tiny.backoff(3, 0); got != tiny.maxBackoff {
+		t.Errorf("backoff with a tiny cap = %v, want %v", got, tiny.maxBackoff)
+	}
+}
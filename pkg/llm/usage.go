@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+)
+
+// Usage is the token consumption of one or more provider calls
+type Usage struct {
+	Provider     Provider `json:"provider,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	InputTokens  int      `json:"inputTokens"`
+	OutputTokens int      `json:"outputTokens"`
+	// CostUSD is filled in by PriceUsage from the configured price table
+	CostUSD float64 `json:"costUSD"`
+}
+
+// Add accumulates other into u
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CostUSD += other.CostUSD
+	if other.Provider != "" {
+		u.Provider = other.Provider
+	}
+	if other.Model != "" {
+		u.Model = other.Model
+	}
+}
+
+// Total returns input plus output tokens
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// PriceUsage sets u.CostUSD from a table of per-million-token prices keyed by
+// model. Dated or suffixed model names (gpt-4o-2024-08-06) match their longest
+// listed prefix. It reports false when tokens were used by a model without a price.
+func PriceUsage(pricing map[string]config.ModelPrice, u *Usage) bool {
+	if u.InputTokens == 0 && u.OutputTokens == 0 {
+		// Cached and replayed analyses cost nothing, whatever the model
+		u.CostUSD = 0
+		return true
+	}
+	price, ok := pricing[u.Model]
+	if !ok {
+		best := ""
+		for model, p := range pricing {
+			if strings.HasPrefix(u.Model, model) && len(model) > len(best) {
+				best, price, ok = model, p, true
+			}
+		}
+	}
+	if !ok {
+		return false
+	}
+
+	u.CostUSD = (float64(u.InputTokens)*price.Input + float64(u.OutputTokens)*price.Output) / 1e6
+	return true
+}
+
+// PriceAnalysis prices the usage of an analysis and of its triage pass, which
+// may come from a different model. It reports false if any model has no price.
+func PriceAnalysis(pricing map[string]config.ModelPrice, a *Analysis) bool {
+	priced := PriceUsage(pricing, &a.Usage)
+	for _, part := range a.parts() {
+		if !PriceAnalysis(pricing, part) {
+			priced = false
+		}
+	}
+	return priced
+}
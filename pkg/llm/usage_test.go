@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+)
+
+func TestPriceUsage(t *testing.T) {
+	pricing := map[string]config.ModelPrice{
+		"gpt-4o":      {Input: 2.5, Output: 10},
+		"gpt-4o-mini": {Input: 0.15, Output: 0.6},
+	}
+
+	tests := []struct {
+		model  string
+		priced bool
+		cost   float64
+	}{
+		{"gpt-4o", true, 2.5 + 10},
+		{"gpt-4o-2024-08-06", true, 2.5 + 10},
+		{"gpt-4o-mini-2024-07-18", true, 0.15 + 0.6},
+		{"llama-3.1-8b", false, 0},
+	}
+
+	for _, tt := range tests {
+		u := Usage{Model: tt.model, InputTokens: 1e6, OutputTokens: 1e6}
+		if priced := PriceUsage(pricing, &u); priced != tt.priced {
+			t.Errorf("PriceUsage(%s) = %v, want %v", tt.model, priced, tt.priced)
+		}
+		if math.Abs(u.CostUSD-tt.cost) > 1e-9 {
+			t.Errorf("%s: CostUSD = %v, want %v", tt.model, u.CostUSD, tt.cost)
+		}
+	}
+
+	// A replayed or cached analysis used no tokens and needs no price
+	if u := (Usage{}); !PriceUsage(pricing, &u) || u.CostUSD != 0 {
+		t.Errorf("PriceUsage of no usage = false or $%v, want priced at zero", u.CostUSD)
+	}
+}
+
+func TestPriceAnalysisIncludesTriage(t *testing.T) {
+	pricing := map[string]config.ModelPrice{
+		"claude-haiku":  {Input: 1, Output: 5},
+		"claude-sonnet": {Input: 3, Output: 15},
+	}
+	a := &Analysis{
+		Usage:  Usage{Model: "claude-sonnet", InputTokens: 1e6},
+		Triage: &Analysis{Usage: Usage{Model: "claude-haiku", InputTokens: 1e6}},
+	}
+
+	if !PriceAnalysis(pricing, a) {
+		t.Fatal("PriceAnalysis reported a missing price")
+	}
+	if total := a.TotalUsage(); math.Abs(total.CostUSD-4) > 1e-9 || total.InputTokens != 2e6 {
+		t.Errorf("TotalUsage() = %+v, want $4 over 2M input tokens", total)
+	}
+}
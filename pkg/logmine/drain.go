@@ -0,0 +1,205 @@
+package logmine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// wildcard replaces the variable tokens of a template
+	wildcard = "<*>"
+	// similarityThreshold is the share of equal tokens for a line to join a template
+	similarityThreshold = 0.5
+	// minSavings is the share of lines compression must save to be worth the
+	// less literal output
+	minSavings = 0.2
+)
+
+// timestampRes match the timestamp prefixes of common log formats
+var timestampRes = []*regexp.Regexp{
+	// RFC 3339 / ISO 8601, optionally bracketed
+	regexp.MustCompile(`^\[?\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?\]?\s+`),
+	// klog: I0102 15:04:05.000000
+	regexp.MustCompile(`^[IWEF]\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+`),
+	// syslog: Jan  2 15:04:05
+	regexp.MustCompile(`^[A-Z][a-z]{2} +\d{1,2} \d{2}:\d{2}:\d{2}\s+`),
+	// Bare time: 15:04:05.000
+	regexp.MustCompile(`^\[?\d{2}:\d{2}:\d{2}(?:[.,]\d+)?\]?\s+`),
+}
+
+// Template is a group of log lines that differ only in their variable parts
+type Template struct {
+	Tokens []string
+	Count  int
+	// First and Last are the timestamps of the first and last line, if they have one
+	First string
+	Last  string
+	// Example is the message of the first line of the group, without the
+	// timestamp already shown by First and Last
+	Example string
+
+	// last is the position of the group's last line, for chronological output
+	last int
+}
+
+// Pattern returns the template with variable tokens shown as <*>
+func (t *Template) Pattern() string {
+	return strings.Join(t.Tokens, " ")
+}
+
+// Miner groups log lines into templates, following Drain: lines are routed by
+// token count and first token, then join the most similar template of their
+// group or start a new one
+type Miner struct {
+	groups    map[string][]*Template
+	templates []*Template
+	lines     int
+}
+
+// NewMiner creates an empty miner
+func NewMiner() *Miner {
+	return &Miner{groups: map[string][]*Template{}}
+}
+
+// Add assigns a log line to a template
+func (m *Miner) Add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	m.lines++
+	timestamp, message := splitTimestamp(line)
+	tokens := tokenize(message)
+
+	key := fmt.Sprintf("%d %s", len(tokens), firstToken(tokens))
+	var best *Template
+	bestScore := 0.0
+	for _, t := range m.groups[key] {
+		if score := similarity(t.Tokens, tokens); score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+
+	if best == nil || bestScore < similarityThreshold {
+		best = &Template{Tokens: tokens, Example: message, First: timestamp}
+		m.groups[key] = append(m.groups[key], best)
+		m.templates = append(m.templates, best)
+	} else {
+		for i, token := range tokens {
+			if best.Tokens[i] != token {
+				best.Tokens[i] = wildcard
+			}
+		}
+	}
+	best.Count++
+	best.Last = timestamp
+	best.last = m.lines
+}
+
+// Templates returns the templates ordered by their last line, so the output
+// still ends with what was logged last
+func (m *Miner) Templates() []*Template {
+	templates := append([]*Template{}, m.templates...)
+	sort.SliceStable(templates, func(i, j int) bool {
+		return templates[i].last < templates[j].last
+	})
+	return templates
+}
+
+// Compress replaces repeated log lines by their template with a count, the
+// first and last timestamps and one example. Lines that occur once are kept
+// verbatim but for their timestamp. Logs that barely repeat are returned unchanged.
+func Compress(logs string) (string, int, int) {
+	m := NewMiner()
+	for _, line := range strings.Split(logs, "\n") {
+		m.Add(line)
+	}
+	templates := m.Templates()
+	if m.lines == 0 || float64(len(templates)) > float64(m.lines)*(1-minSavings) {
+		return logs, m.lines, m.lines
+	}
+
+	var b strings.Builder
+	for _, t := range templates {
+		if t.Count == 1 {
+			fmt.Fprintf(&b, "%s\n", t.Example)
+			continue
+		}
+		fmt.Fprintf(&b, "[%dx", t.Count)
+		if t.First != "" {
+			fmt.Fprintf(&b, ", %s to %s", t.First, t.Last)
+		}
+		fmt.Fprintf(&b, "] %s\n    e.g. %s\n", t.Pattern(), t.Example)
+	}
+	return b.String(), m.lines, len(templates)
+}
+
+// StripTimestamps removes the RFC 3339 timestamps the kubelet prefixes to each
+// line of logs fetched with timestamps
+func StripTimestamps(logs string) string {
+	lines := strings.Split(logs, "\n")
+	for i, line := range lines {
+		if prefix, rest, ok := strings.Cut(line, " "); ok {
+			if _, err := time.Parse(time.RFC3339Nano, prefix); err == nil {
+				lines[i] = rest
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func splitTimestamp(line string) (string, string) {
+	for _, re := range timestampRes {
+		if loc := re.FindStringIndex(line); loc != nil {
+			return strings.TrimSpace(line[:loc[1]]), line[loc[1]:]
+		}
+	}
+	return "", line
+}
+
+// tokenize splits a message at whitespace and masks tokens carrying numbers
+// (IDs, durations, addresses); in key=value tokens only the value is masked
+func tokenize(message string) []string {
+	tokens := strings.Fields(message)
+	for i, token := range tokens {
+		if key, value, ok := strings.Cut(token, "="); ok && key != "" {
+			if hasDigit(value) {
+				tokens[i] = key + "=" + wildcard
+			}
+			continue
+		}
+		if hasDigit(token) {
+			tokens[i] = wildcard
+		}
+	}
+	return tokens
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
+
+func firstToken(tokens []string) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	return tokens[0]
+}
+
+// similarity is the share of positions where the template and the line agree;
+// positions already generalized to a wildcard match anything
+func similarity(template, tokens []string) float64 {
+	if len(tokens) == 0 {
+		return 1
+	}
+	equal := 0
+	for i, token := range tokens {
+		if template[i] == token || template[i] == wildcard {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(tokens))
+}
@@ -0,0 +1,132 @@
+package logmine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"connection refused", "connection refused"},
+		{"retry 3 of 5 after 250ms", "retry <*> of <*> after <*>"},
+		{"dial tcp 10.0.0.12:5432: refused", "dial tcp <*> refused"},
+		{"request id=7f3a status=500 path=/healthz", "request id=<*> status=<*> path=/healthz"},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(tokenize(tt.message), " "); got != tt.want {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTimestamp(t *testing.T) {
+	tests := []struct {
+		line          string
+		wantTimestamp string
+		wantMessage   string
+	}{
+		{"2024-01-02T15:04:05.123Z starting", "2024-01-02T15:04:05.123Z", "starting"},
+		{"[2024-01-02 15:04:05,123] starting", "[2024-01-02 15:04:05,123]", "starting"},
+		{"E0102 15:04:05.000000 1 main.go:10] failed", "E0102 15:04:05.000000", "1 main.go:10] failed"},
+		{"Jan  2 15:04:05 host sshd: ok", "Jan  2 15:04:05", "host sshd: ok"},
+		{"15:04:05.000 tick", "15:04:05.000", "tick"},
+		{"no timestamp here", "", "no timestamp here"},
+	}
+
+	for _, tt := range tests {
+		timestamp, message := splitTimestamp(tt.line)
+		if timestamp != tt.wantTimestamp || message != tt.wantMessage {
+			t.Errorf("splitTimestamp(%q) = %q, %q, want %q, %q", tt.line, timestamp, message, tt.wantTimestamp, tt.wantMessage)
+		}
+	}
+}
+
+func TestMinerGroupsVariableLines(t *testing.T) {
+	m := NewMiner()
+	for i := 0; i < 10; i++ {
+		m.Add(fmt.Sprintf("2024-01-02T15:04:%02dZ connecting to db-%d.internal:5432 attempt %d", i, i, i))
+	}
+	m.Add("2024-01-02T15:05:00Z giving up")
+
+	templates := m.Templates()
+	if len(templates) != 2 {
+		t.Fatalf("got %d templates, want 2", len(templates))
+	}
+	first := templates[0]
+	if first.Count != 10 {
+		t.Errorf("Count = %d, want 10", first.Count)
+	}
+	if first.Pattern() != "connecting to <*> attempt <*>" {
+		t.Errorf("Pattern = %q", first.Pattern())
+	}
+	if first.First != "2024-01-02T15:04:00Z" || first.Last != "2024-01-02T15:04:09Z" {
+		t.Errorf("First, Last = %q, %q", first.First, first.Last)
+	}
+	if templates[1].Pattern() != "giving up" {
+		t.Errorf("last template = %q, want the last line", templates[1].Pattern())
+	}
+}
+
+func TestCompress(t *testing.T) {
+	var repeated strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&repeated, "2024-01-02T15:04:%02dZ health check ok latency=%dms\n", i, i)
+	}
+	repeated.WriteString("2024-01-02T15:05:00Z panic: config missing\n")
+
+	tests := []struct {
+		name          string
+		logs          string
+		wantLines     int
+		wantTemplates int
+		wantContains  string
+	}{
+		{"repeated lines", repeated.String(), 51, 2, "[50x, 2024-01-02T15:04:00Z to 2024-01-02T15:04:49Z] health check ok latency=<*>"},
+		{"distinct lines are left unchanged", "starting\nlistening on :8080\nshutting down\n", 3, 3, "listening on :8080"},
+		{"empty", "", 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, lines, templates := Compress(tt.logs)
+			if lines != tt.wantLines || templates != tt.wantTemplates {
+				t.Errorf("lines, templates = %d, %d, want %d, %d", lines, templates, tt.wantLines, tt.wantTemplates)
+			}
+			if !strings.Contains(compressed, tt.wantContains) {
+				t.Errorf("output does not contain %q:\n%s", tt.wantContains, compressed)
+			}
+			if templates == lines && compressed != tt.logs {
+				t.Errorf("uncompressible logs were changed:\n%s", compressed)
+			}
+		})
+	}
+}
+
+func TestCompressExamplesOmitTimestamps(t *testing.T) {
+	var logs strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&logs, "2024-01-02T15:04:%02d.123456789Z retrying request id=%d\n", i, i)
+	}
+	logs.WriteString("2024-01-02T15:05:00.000000001Z fatal: giving up\n")
+
+	compressed, _, _ := Compress(logs.String())
+	want := "[10x, 2024-01-02T15:04:00.123456789Z to 2024-01-02T15:04:09.123456789Z] retrying request id=<*>\n" +
+		"    e.g. retrying request id=0\n" +
+		"fatal: giving up\n"
+	if compressed != want {
+		t.Errorf("Compress() = %q, want %q", compressed, want)
+	}
+}
+
+func TestStripTimestamps(t *testing.T) {
+	logs := "2024-01-02T15:04:05.123456789Z panic: boom\n2024-01-02T15:04:05.2Z \tgoroutine 1 [running]:\nnot a timestamp\n"
+	want := "panic: boom\n\tgoroutine 1 [running]:\nnot a timestamp\n"
+	if got := StripTimestamps(logs); got != want {
+		t.Errorf("StripTimestamps() = %q, want %q", got, want)
+	}
+}
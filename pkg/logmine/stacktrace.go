@@ -0,0 +1,393 @@
+package logmine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxStackTraces bounds the distinct traces kept; the most recent win
+	maxStackTraces = 5
+	// maxFrames is how many of the innermost frames are kept per trace
+	maxFrames = 8
+	// maxMessageLen bounds exception messages, which may embed whole payloads,
+	// in characters
+	maxMessageLen = 500
+	// pyTraceback starts every Python traceback
+	pyTraceback = "Traceback (most recent call last):"
+)
+
+var (
+	goPanicRe     = regexp.MustCompile(`^(panic|fatal error): (.*)$`)
+	goGoroutineRe = regexp.MustCompile(`^goroutine \d+ \[.*\]:$`)
+	goFuncRe      = regexp.MustCompile(`^(?:created by )?[\w./*()\[\]{}-]+(?:\(.*\))?(?: in goroutine \d+)?$`)
+	goFileRe      = regexp.MustCompile(`^\s+(\S+\.go:\d+)`)
+
+	atFrameRe    = regexp.MustCompile(`^\s+at\s+(.+)$`)
+	jvmFrameRe   = regexp.MustCompile(`\.(?:java|kt|scala|groovy|clj):\d+\)$|\((?:Native Method|Unknown Source)\)$`)
+	nodeFrameRe  = regexp.MustCompile(`\.(?:[cm]?js|ts):\d+:\d+\)?$|\(?node:`)
+	causedByRe   = regexp.MustCompile(`^Caused by: (.*)$`)
+	suppressedRe = regexp.MustCompile(`^\s+Suppressed: `)
+	moreFramesRe = regexp.MustCompile(`^\s+\.\.\. \d+ (?:more|common frames omitted)`)
+
+	pyFrameRe = regexp.MustCompile(`^\s+File "([^"]+)", line (\d+), in (.+)$`)
+	pyChainRe = regexp.MustCompile(`^(?:During handling of the above exception|The above exception was the direct cause)`)
+
+	rustPanicRe = regexp.MustCompile(`^thread '([^']*)' panicked at (?:'(.*)', )?(\S+?):?$`)
+	rustFrameRe = regexp.MustCompile(`^\s+\d+: (.+)$`)
+)
+
+// StackTrace is an exception or panic found in container logs
+type StackTrace struct {
+	// Language is go, java (also Kotlin and Scala), python, node or rust
+	Language string
+	Type     string
+	Message  string
+	// Causes is the chain of underlying exceptions, outermost first
+	Causes []string
+	// Frames are the innermost frames of the root cause, innermost first
+	Frames []string
+	// Goroutines is the number of goroutines in a Go dump
+	Goroutines int
+	// Count is how often the same trace occurred in the logs
+	Count int
+}
+
+// Summary returns the exception type and message
+func (t *StackTrace) Summary() string {
+	switch {
+	case t.Type == "":
+		return t.Message
+	case t.Message == "":
+		return t.Type
+	default:
+		return t.Type + ": " + t.Message
+	}
+}
+
+// key identifies repeats of the same trace, ignoring numbers in the message
+func (t *StackTrace) key() string {
+	return strings.Join([]string{
+		t.Language, t.Type, strings.Join(tokenize(t.Message), " "),
+		strings.Join(t.Causes, "\n"), strings.Join(t.Frames, "\n"),
+	}, "\x00")
+}
+
+// ExtractStackTraces finds Go panics and goroutine dumps, Java/Kotlin exceptions
+// with their "Caused by" chains, Python tracebacks, Node.js errors and Rust
+// panics anywhere in the logs. Repeats are counted once and the most recent
+// distinct traces are returned, oldest first.
+func ExtractStackTraces(logs string) []*StackTrace {
+	lines := strings.Split(strings.ReplaceAll(logs, "\r\n", "\n"), "\n")
+
+	var traces []*StackTrace
+	for i := 0; i < len(lines); {
+		var t *StackTrace
+		next := i + 1
+		switch {
+		case goPanicRe.MatchString(lines[i]):
+			t, next = parseGoPanic(lines, i)
+		case strings.HasSuffix(strings.TrimSpace(lines[i]), pyTraceback):
+			t, next = parsePython(lines, i)
+		case rustPanicRe.MatchString(lines[i]):
+			t, next = parseRust(lines, i)
+		case i+1 < len(lines) && !atFrameRe.MatchString(lines[i]) && atFrameRe.MatchString(lines[i+1]):
+			t, next = parseThrown(lines, i)
+		}
+		if next <= i {
+			next = i + 1
+		}
+		i = next
+		if t == nil {
+			continue
+		}
+		if message := []rune(t.Message); len(message) > maxMessageLen {
+			t.Message = string(message[:maxMessageLen]) + " [...]"
+		}
+		traces = addTrace(traces, t)
+	}
+
+	if len(traces) > maxStackTraces {
+		traces = traces[len(traces)-maxStackTraces:]
+	}
+	return traces
+}
+
+// addTrace counts a repeat of a known trace and moves it to the end, so the
+// slice stays ordered by most recent occurrence
+func addTrace(traces []*StackTrace, t *StackTrace) []*StackTrace {
+	key := t.key()
+	for i, known := range traces {
+		if known.key() == key {
+			known.Count++
+			return append(append(traces[:i], traces[i+1:]...), known)
+		}
+	}
+	t.Count = 1
+	return append(traces, t)
+}
+
+// parseGoPanic reads a panic or fatal error and the goroutine dump after it.
+// Frames come from the first goroutine, the one that panicked, without the
+// runtime's own frames.
+func parseGoPanic(lines []string, i int) (*StackTrace, int) {
+	m := goPanicRe.FindStringSubmatch(lines[i])
+	t := &StackTrace{Language: "go", Type: m[1], Message: m[2]}
+
+	// The signal, nested panics and a blank line precede the first goroutine
+	j := i + 1
+	for ; j < len(lines) && j <= i+4 && !goGoroutineRe.MatchString(lines[j]); j++ {
+		line := strings.TrimSpace(lines[j])
+		switch {
+		case strings.HasPrefix(line, "[signal "):
+			t.Message += " " + line
+		case strings.HasPrefix(line, "panic: "):
+			t.Causes = append(t.Causes, line)
+		}
+	}
+	if j >= len(lines) || !goGoroutineRe.MatchString(lines[j]) {
+		return nil, i + 1
+	}
+
+	var function string
+	for ; j < len(lines); j++ {
+		line := lines[j]
+		switch {
+		case goGoroutineRe.MatchString(line):
+			t.Goroutines++
+		case strings.TrimSpace(line) == "":
+		case goFileRe.MatchString(line):
+			if t.Goroutines == 1 && function != "" && len(t.Frames) < maxFrames {
+				t.Frames = append(t.Frames, function+" at "+goFileRe.FindStringSubmatch(line)[1])
+			}
+			function = ""
+		case goFuncRe.MatchString(line):
+			function = goFunction(line)
+		default:
+			return t, j
+		}
+	}
+	return t, j
+}
+
+// goFunction strips the arguments from a goroutine frame and skips the
+// runtime's panic machinery
+func goFunction(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "created by ") {
+		return ""
+	}
+	if strings.HasSuffix(line, ")") {
+		if open := strings.LastIndex(line, "("); open > 0 {
+			line = line[:open]
+		}
+	}
+	if line == "panic" || strings.HasPrefix(line, "runtime.") {
+		return ""
+	}
+	return line
+}
+
+// parseThrown reads an exception line followed by "at" frames: JVM exceptions
+// with their "Caused by" chain, or Node.js errors
+func parseThrown(lines []string, i int) (*StackTrace, int) {
+	t := &StackTrace{}
+	t.Type, t.Message = splitException(lines[i])
+
+	var frames, current []string
+	jvm, node, suppressed := false, false, false
+	j := i + 1
+	for ; j < len(lines); j++ {
+		line := lines[j]
+		if m := atFrameRe.FindStringSubmatch(line); m != nil {
+			jvm = jvm || jvmFrameRe.MatchString(m[1])
+			node = node || nodeFrameRe.MatchString(m[1])
+			if !suppressed && len(current) < maxFrames {
+				current = append(current, strings.TrimSpace(m[1]))
+			}
+			continue
+		}
+		if moreFramesRe.MatchString(line) {
+			continue
+		}
+		if suppressedRe.MatchString(line) {
+			suppressed = true
+			continue
+		}
+		if m := causedByRe.FindStringSubmatch(line); m != nil {
+			typ, msg := splitException(m[1])
+			t.Causes = append(t.Causes, (&StackTrace{Type: typ, Message: msg}).Summary())
+			if len(current) > 0 {
+				frames = current
+			}
+			current, suppressed = nil, false
+			continue
+		}
+		break
+	}
+	if len(current) > 0 {
+		frames = current
+	}
+	t.Frames = frames
+
+	switch {
+	case jvm:
+		t.Language = "java"
+	case node:
+		t.Language = "node"
+	default:
+		// "at" lines of an unknown runtime, or prose that happens to start with "at"
+		return nil, j
+	}
+	return t, j
+}
+
+// parsePython reads a traceback and the tracebacks chained to it. The last
+// exception is the one that was raised; earlier ones are its causes.
+func parsePython(lines []string, i int) (*StackTrace, int) {
+	var chain []*StackTrace
+	j := i
+	for j < len(lines) && strings.HasSuffix(strings.TrimSpace(lines[j]), pyTraceback) {
+		t := &StackTrace{Language: "python"}
+		var frames []string
+		for j++; j < len(lines); j++ {
+			line := lines[j]
+			if m := pyFrameRe.FindStringSubmatch(line); m != nil {
+				frames = append(frames, fmt.Sprintf("%s (%s:%s)", m[3], m[1], m[2]))
+				continue
+			}
+			// Source lines and the carets under them are indented
+			if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+				continue
+			}
+			break
+		}
+		if j < len(lines) {
+			t.Type, t.Message = splitException(lines[j])
+			j++
+		}
+		// Python lists the innermost frame last
+		for k := len(frames) - 1; k >= 0 && len(t.Frames) < maxFrames; k-- {
+			t.Frames = append(t.Frames, frames[k])
+		}
+		chain = append(chain, t)
+
+		k := j
+		for k < len(lines) && strings.TrimSpace(lines[k]) == "" {
+			k++
+		}
+		if k >= len(lines) || !pyChainRe.MatchString(lines[k]) {
+			break
+		}
+		for j = k + 1; j < len(lines) && strings.TrimSpace(lines[j]) == ""; j++ {
+		}
+	}
+
+	t := chain[len(chain)-1]
+	for k := len(chain) - 2; k >= 0; k-- {
+		t.Causes = append(t.Causes, chain[k].Summary())
+	}
+	if len(chain[0].Frames) > 0 {
+		t.Frames = chain[0].Frames
+	}
+	return t, j
+}
+
+// parseRust reads a panic and, with RUST_BACKTRACE set, its backtrace without
+// the frames of std and core
+func parseRust(lines []string, i int) (*StackTrace, int) {
+	m := rustPanicRe.FindStringSubmatch(lines[i])
+	t := &StackTrace{Language: "rust", Type: "panic", Message: m[2], Frames: []string{m[3]}}
+
+	j := i + 1
+	// Since Rust 1.73 the message follows on its own line
+	if t.Message == "" && j < len(lines) {
+		t.Message = strings.TrimSpace(lines[j])
+		j++
+	}
+	t.Message = fmt.Sprintf("thread '%s': %s", m[1], t.Message)
+	for j < len(lines) && strings.HasPrefix(lines[j], "note: ") {
+		j++
+	}
+	if j >= len(lines) || strings.TrimSpace(lines[j]) != "stack backtrace:" {
+		return t, j
+	}
+
+	for j++; j < len(lines); j++ {
+		line := lines[j]
+		if m := rustFrameRe.FindStringSubmatch(line); m != nil {
+			if !rustInternal(m[1]) && len(t.Frames) < maxFrames {
+				t.Frames = append(t.Frames, m[1])
+			}
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "at ") {
+			continue
+		}
+		if strings.HasPrefix(line, "note: ") {
+			j++
+		}
+		break
+	}
+	return t, j
+}
+
+func rustInternal(function string) bool {
+	for _, prefix := range []string{"std::", "core::", "alloc::", "rust_begin_unwind", "__rust", "<alloc::", "<core::", "<std::"} {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitException splits "pkg.SomeException: message" into type and message.
+// Lines whose prefix is not a single identifier are kept whole as the message.
+func splitException(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if rest, ok := strings.CutPrefix(line, "Exception in thread "); ok {
+		// Exception in thread "main" java.lang.IllegalStateException: ...
+		if end := strings.Index(rest, "\" "); end >= 0 {
+			line = rest[end+2:]
+		}
+	}
+	line = strings.TrimPrefix(line, "Uncaught ")
+
+	if typ, msg, ok := strings.Cut(line, ": "); ok && typ != "" && !strings.ContainsAny(typ, " \t") {
+		return typ, strings.TrimSpace(msg)
+	}
+	if !strings.ContainsAny(line, " \t") {
+		return line, ""
+	}
+	return "", line
+}
+
+// RenderStackTraces formats traces as compact blocks for the prompt
+func RenderStackTraces(traces []*StackTrace) string {
+	var b strings.Builder
+	for _, t := range traces {
+		fmt.Fprintf(&b, "[%s] %s", t.Language, t.Summary())
+		var notes []string
+		if t.Count > 1 {
+			notes = append(notes, fmt.Sprintf("seen %d times", t.Count))
+		}
+		if t.Goroutines > 1 {
+			notes = append(notes, fmt.Sprintf("%d goroutines", t.Goroutines))
+		}
+		if len(notes) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(notes, ", "))
+		}
+		b.WriteString("\n")
+		for _, cause := range t.Causes {
+			fmt.Fprintf(&b, "  Caused by: %s\n", cause)
+		}
+		if len(t.Frames) > 0 {
+			b.WriteString("  Top frames:\n")
+			for _, frame := range t.Frames {
+				fmt.Fprintf(&b, "    %s\n", frame)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,206 @@
+package logmine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const goPanicLogs = `starting server on :8080
+panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a1b2c]
+
+goroutine 1 [running]:
+main.(*Server).handle(0x0, {0xc000010000, 0x5})
+	/app/server.go:42 +0x1c
+main.main()
+	/app/main.go:17 +0x85
+
+goroutine 7 [chan receive]:
+main.worker()
+	/app/worker.go:9 +0x20
+exit status 2`
+
+const javaLogs = `2024-01-01 ERROR request failed
+Exception in thread "main" java.lang.IllegalStateException: Failed to start
+	at com.example.App.start(App.java:20)
+	at com.example.App.main(App.java:10)
+Caused by: java.sql.SQLException: Connection refused
+	at org.postgresql.Driver.connect(Driver.java:300)
+	at com.example.Db.open(Db.java:55)
+	... 2 more
+shutting down`
+
+const pythonLogs = `Traceback (most recent call last):
+  File "/app/db.py", line 10, in connect
+    sock.connect(addr)
+ConnectionRefusedError: [Errno 111] Connection refused
+
+During handling of the above exception, another exception occurred:
+
+Traceback (most recent call last):
+  File "/app/main.py", line 5, in <module>
+    run()
+  File "/app/main.py", line 3, in run
+    db.connect()
+RuntimeError: database unavailable`
+
+const nodeLogs = `TypeError: Cannot read properties of undefined (reading 'id')
+    at getUser (/app/src/users.js:12:20)
+    at processTicksAndRejections (node:internal/process/task_queues:95:5)`
+
+const rustLogs = `thread 'main' panicked at src/main.rs:5:10:
+called ` + "`Option::unwrap()`" + ` on a ` + "`None`" + ` value
+stack backtrace:
+   0: rust_begin_unwind
+   1: core::panicking::panic
+   2: app::load_config
+             at ./src/config.rs:12:5
+   3: app::main
+note: Some details are omitted, run with ` + "`RUST_BACKTRACE=full`" + ` for a verbose backtrace.`
+
+func TestExtractStackTraces(t *testing.T) {
+	tests := []struct {
+		name string
+		logs string
+		want []*StackTrace
+	}{
+		{
+			name: "go panic",
+			logs: goPanicLogs,
+			want: []*StackTrace{{
+				Language: "go", Type: "panic",
+				Message:    "runtime error: invalid memory address or nil pointer dereference [signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a1b2c]",
+				Frames:     []string{"main.(*Server).handle at /app/server.go:42", "main.main at /app/main.go:17"},
+				Goroutines: 2, Count: 1,
+			}},
+		},
+		{
+			name: "java with caused by",
+			logs: javaLogs,
+			want: []*StackTrace{{
+				Language: "java", Type: "java.lang.IllegalStateException", Message: "Failed to start",
+				Causes: []string{"java.sql.SQLException: Connection refused"},
+				Frames: []string{"org.postgresql.Driver.connect(Driver.java:300)", "com.example.Db.open(Db.java:55)"},
+				Count:  1,
+			}},
+		},
+		{
+			name: "python chained tracebacks",
+			logs: pythonLogs,
+			want: []*StackTrace{{
+				Language: "python", Type: "RuntimeError", Message: "database unavailable",
+				Causes: []string{"ConnectionRefusedError: [Errno 111] Connection refused"},
+				Frames: []string{"connect (/app/db.py:10)"},
+				Count:  1,
+			}},
+		},
+		{
+			name: "node",
+			logs: nodeLogs,
+			want: []*StackTrace{{
+				Language: "node", Type: "TypeError", Message: "Cannot read properties of undefined (reading 'id')",
+				Frames: []string{"getUser (/app/src/users.js:12:20)", "processTicksAndRejections (node:internal/process/task_queues:95:5)"},
+				Count:  1,
+			}},
+		},
+		{
+			name: "rust with backtrace",
+			logs: rustLogs,
+			want: []*StackTrace{{
+				Language: "rust", Type: "panic",
+				Message: "thread 'main': called `Option::unwrap()` on a `None` value",
+				Frames:  []string{"src/main.rs:5:10", "app::load_config", "app::main"},
+				Count:   1,
+			}},
+		},
+		{
+			name: "prose starting with at is ignored",
+			logs: "Retrying connection\n  at most 3 times\n  at 5s intervals",
+		},
+		{
+			name: "no traces",
+			logs: "GET /healthz 200\nGET /ready 200",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractStackTraces(tt.logs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractStackTraces() = %s\nwant %s", RenderStackTraces(got), RenderStackTraces(tt.want))
+			}
+		})
+	}
+}
+
+func TestExtractStackTracesCountsRepeats(t *testing.T) {
+	repeated := strings.ReplaceAll(nodeLogs, "reading 'id'", "reading 'name'")
+	logs := strings.Join([]string{nodeLogs, "retrying", repeated, pythonLogs, nodeLogs}, "\n")
+
+	traces := ExtractStackTraces(logs)
+	if len(traces) != 3 {
+		t.Fatalf("found %d traces, want 3:\n%s", len(traces), RenderStackTraces(traces))
+	}
+	// The most recent occurrence decides the order
+	if last := traces[2]; last.Language != "node" || last.Count != 2 {
+		t.Errorf("last trace = %s (seen %d), want the repeated node error", last.Summary(), last.Count)
+	}
+	if !strings.Contains(RenderStackTraces(traces), "seen 2 times") {
+		t.Errorf("RenderStackTraces() does not report the repeat:\n%s", RenderStackTraces(traces))
+	}
+}
+
+func TestExtractStackTracesLimits(t *testing.T) {
+	var blocks []string
+	for i := 0; i < maxStackTraces+2; i++ {
+		blocks = append(blocks, "Error"+strings.Repeat("x", i)+": "+strings.Repeat("payload ", maxMessageLen)+"\n    at f (/app/a.js:1:1)")
+	}
+
+	traces := ExtractStackTraces(strings.Join(blocks, "\n"))
+	if len(traces) != maxStackTraces {
+		t.Fatalf("kept %d traces, want %d", len(traces), maxStackTraces)
+	}
+	if traces[len(traces)-1].Type != "Error"+strings.Repeat("x", maxStackTraces+1) {
+		t.Errorf("last trace = %s, want the most recent one", traces[len(traces)-1].Type)
+	}
+	if n := len(traces[0].Message); n > maxMessageLen+len(" [...]") {
+		t.Errorf("message has %d bytes, want at most %d", n, maxMessageLen)
+	}
+}
+
+func TestExtractStackTracesCutsMessagesAtRuneBoundaries(t *testing.T) {
+	logs := "Error: " + strings.Repeat("ошибка ", maxMessageLen) + "\n    at f (/app/a.js:1:1)"
+
+	traces := ExtractStackTraces(logs)
+	if len(traces) != 1 {
+		t.Fatalf("found %d traces, want 1", len(traces))
+	}
+	message := traces[0].Message
+	if !utf8.ValidString(message) {
+		t.Errorf("message is not valid UTF-8: %q", message)
+	}
+	if n := utf8.RuneCountInString(message); n != maxMessageLen+len(" [...]") {
+		t.Errorf("message has %d characters, want %d", n, maxMessageLen+len(" [...]"))
+	}
+}
+
+func TestSplitException(t *testing.T) {
+	tests := []struct {
+		line, typ, msg string
+	}{
+		{"java.io.IOException: broken pipe", "java.io.IOException", "broken pipe"},
+		{`Exception in thread "main" java.lang.Error: boom`, "java.lang.Error", "boom"},
+		{"Uncaught TypeError: x is not a function", "TypeError", "x is not a function"},
+		{"KeyboardInterrupt", "KeyboardInterrupt", ""},
+		{"something went wrong: badly", "", "something went wrong: badly"},
+	}
+
+	for _, tt := range tests {
+		typ, msg := splitException(tt.line)
+		if typ != tt.typ || msg != tt.msg {
+			t.Errorf("splitException(%q) = %q, %q, want %q, %q", tt.line, typ, msg, tt.typ, tt.msg)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TruncateFrom selects which end of a section is dropped when it must shrink
+type TruncateFrom int
+
+const (
+	// TruncateHead drops the oldest lines and keeps the tail (logs, events)
+	TruncateHead TruncateFrom = iota
+	// TruncateTail drops the last lines and keeps the head (status, descriptions)
+	TruncateTail
+)
+
+// Section is one block of incident context
+type Section struct {
+	Name    string
+	Title   string
+	Content string
+	// Priority orders truncation: lower priority sections are shrunk first
+	Priority int
+	// MinTokens is kept even under budget pressure unless the section must be dropped
+	MinTokens int
+	Truncate  TruncateFrom
+	// Pinned sections are never truncated; keep them small (e.g. extracted stack traces)
+	Pinned bool
+	// Untrusted content comes from the workload and is fenced as data in the prompt
+	Untrusted bool
+	// Warning is shown next to the title, e.g. when the content looks like a prompt injection
+	Warning string
+
+	// Tokens is the estimated size after assembly
+	Tokens int
+	// Truncated is set when lines were removed to fit the budget
+	Truncated bool
+}
+
+// Assembler fits context sections into a token budget
+type Assembler struct {
+	// Budget is the maximum number of tokens for all sections together
+	Budget int
+	// Estimate returns the token count of a string for the target provider
+	Estimate func(string) int
+}
+
+// NewAssembler creates an assembler with the given budget and estimator
+func NewAssembler(budget int, estimate func(string) int) *Assembler {
+	return &Assembler{
+		Budget:   budget,
+		Estimate: estimate,
+	}
+}
+
+// Fit shrinks sections in place until they fit the budget, truncating the least
+// valuable sections first: each is first cut down to MinTokens, then dropped
+// entirely if that is still not enough. Pinned sections are left whole.
+// Sections are returned in input order.
+func (a *Assembler) Fit(sections []*Section) []*Section {
+	total := 0
+	for _, s := range sections {
+		s.Tokens = a.Estimate(s.Content)
+		total += s.Tokens
+	}
+	if a.Budget <= 0 || total <= a.Budget {
+		return sections
+	}
+
+	byPriority := make([]*Section, len(sections))
+	copy(byPriority, sections)
+	sort.SliceStable(byPriority, func(i, j int) bool {
+		return byPriority[i].Priority < byPriority[j].Priority
+	})
+
+	// Pass 1: shrink sections to their minimum, least valuable first
+	for _, s := range byPriority {
+		if total <= a.Budget {
+			break
+		}
+		if s.Pinned {
+			continue
+		}
+		target := s.Tokens - (total - a.Budget)
+		if target < s.MinTokens {
+			target = s.MinTokens
+		}
+		if target >= s.Tokens {
+			continue
+		}
+		total -= s.Tokens
+		a.truncate(s, target)
+		total += s.Tokens
+	}
+
+	// Pass 2: drop sections entirely, least valuable first
+	for _, s := range byPriority {
+		if total <= a.Budget {
+			break
+		}
+		if s.Pinned || s.Tokens == 0 {
+			continue
+		}
+		total -= s.Tokens
+		a.truncate(s, 0)
+		total += s.Tokens
+	}
+
+	return sections
+}
+
+// Render formats fitted sections as titled blocks
+func Render(sections []*Section) string {
+	var b strings.Builder
+	for _, s := range sections {
+		if s.Content == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		title := s.Title
+		if s.Warning != "" {
+			title += " (WARNING: " + s.Warning + ")"
+		}
+		fmt.Fprintf(&b, "%s:\n%s", title, strings.TrimRight(s.Body(), "\n"))
+	}
+	return b.String()
+}
+
+// truncate removes whole lines from the configured end of s until it fits
+// within target tokens, leaving an explicit marker in place of the removed lines
+func (a *Assembler) truncate(s *Section, target int) {
+	lines := strings.Split(strings.TrimRight(s.Content, "\n"), "\n")
+	original := len(lines)
+
+	if target <= 0 {
+		s.Content = fmt.Sprintf("[section omitted: %d lines removed to fit the token budget]", original)
+		s.Tokens = a.Estimate(s.Content)
+		s.Truncated = true
+		return
+	}
+
+	// Per-line estimates avoid re-estimating the whole section on every step
+	sizes := make([]int, len(lines))
+	size := 0
+	for i, line := range lines {
+		sizes[i] = a.Estimate(line + "\n")
+		size += sizes[i]
+	}
+	// Reserve room for the marker line
+	target -= a.Estimate(fmt.Sprintf("[... %d lines truncated to fit the token budget ...]\n", original))
+
+	for len(lines) > 1 && size > target {
+		if s.Truncate == TruncateHead {
+			size -= sizes[0]
+			lines, sizes = lines[1:], sizes[1:]
+		} else {
+			size -= sizes[len(sizes)-1]
+			lines, sizes = lines[:len(lines)-1], sizes[:len(sizes)-1]
+		}
+	}
+
+	// A single oversized line (e.g. a JSON log blob) is cut by characters instead
+	if len(lines) == 1 && size > target && target > 0 {
+		runes := []rune(lines[0])
+		keep := len(runes) * target / size
+		if s.Truncate == TruncateHead {
+			runes = runes[len(runes)-keep:]
+		} else {
+			runes = runes[:keep]
+		}
+		lines[0] = string(runes) + " [... line truncated ...]"
+		s.Content = lines[0]
+		s.Tokens = a.Estimate(s.Content)
+		s.Truncated = true
+		if original == 1 {
+			return
+		}
+	}
+
+	removed := original - len(lines)
+	if removed == 0 {
+		return
+	}
+
+	marker := fmt.Sprintf("[... %d lines truncated to fit the token budget ...]", removed)
+	if s.Truncate == TruncateHead {
+		lines = append([]string{marker}, lines...)
+	} else {
+		lines = append(lines, marker)
+	}
+	s.Content = strings.Join(lines, "\n")
+	s.Tokens = a.Estimate(s.Content)
+	s.Truncated = true
+}
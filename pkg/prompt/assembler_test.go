@@ -0,0 +1,151 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// charCount estimates one token per byte, which keeps budgets easy to reason about
+func charCount(s string) int {
+	return len(s)
+}
+
+func numberedLines(prefix string, n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s line %02d", prefix, i+1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestAssemblerFit(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   int
+		sections []*Section
+		// check inspects the fitted sections by name
+		check func(t *testing.T, byName map[string]*Section)
+	}{
+		{
+			name:   "within budget",
+			budget: 10000,
+			sections: []*Section{
+				{Name: "pod", Content: numberedLines("pod", 5), Priority: 40},
+				{Name: "logs", Content: numberedLines("log", 5), Priority: 10},
+			},
+			check: func(t *testing.T, byName map[string]*Section) {
+				for name, s := range byName {
+					if s.Truncated {
+						t.Errorf("%s truncated within budget", name)
+					}
+				}
+			},
+		},
+		{
+			name:   "lowest priority is truncated first, keeping its tail",
+			budget: 600,
+			sections: []*Section{
+				{Name: "pod", Content: numberedLines("pod", 20), Priority: 40, Truncate: TruncateTail},
+				{Name: "logs", Content: numberedLines("log", 40), Priority: 10, MinTokens: 100, Truncate: TruncateHead},
+			},
+			check: func(t *testing.T, byName map[string]*Section) {
+				if byName["pod"].Truncated {
+					t.Error("higher priority section truncated")
+				}
+				logs := byName["logs"]
+				if !logs.Truncated || !strings.HasPrefix(logs.Content, "[... ") || !strings.HasSuffix(logs.Content, "log line 40") {
+					t.Errorf("logs not truncated from the head:\n%s", logs.Content)
+				}
+			},
+		},
+		{
+			name:   "tail truncation keeps the head",
+			budget: 150,
+			sections: []*Section{
+				{Name: "pod", Content: numberedLines("pod", 40), Priority: 40, MinTokens: 100, Truncate: TruncateTail},
+			},
+			check: func(t *testing.T, byName map[string]*Section) {
+				pod := byName["pod"]
+				if !strings.HasPrefix(pod.Content, "pod line 01") || !strings.HasSuffix(pod.Content, "truncated to fit the token budget ...]") {
+					t.Errorf("pod not truncated from the tail:\n%s", pod.Content)
+				}
+			},
+		},
+		{
+			name:   "sections are dropped when their minimum does not fit",
+			budget: 300,
+			sections: []*Section{
+				{Name: "pod", Content: numberedLines("pod", 20), Priority: 40, MinTokens: 250},
+				{Name: "history", Content: numberedLines("history", 20), Priority: 5, MinTokens: 250},
+			},
+			check: func(t *testing.T, byName map[string]*Section) {
+				if !strings.HasPrefix(byName["history"].Content, "[section omitted: ") {
+					t.Errorf("history not dropped:\n%s", byName["history"].Content)
+				}
+			},
+		},
+		{
+			name:   "pinned sections are kept whole",
+			budget: 400,
+			sections: []*Section{
+				{Name: "stacktraces", Content: numberedLines("trace", 20), Priority: 1, Pinned: true},
+				{Name: "logs", Content: numberedLines("log", 40), Priority: 10, MinTokens: 50, Truncate: TruncateHead},
+			},
+			check: func(t *testing.T, byName map[string]*Section) {
+				if byName["stacktraces"].Truncated || byName["stacktraces"].Content != numberedLines("trace", 20) {
+					t.Errorf("pinned section truncated:\n%s", byName["stacktraces"].Content)
+				}
+				if !byName["logs"].Truncated {
+					t.Error("logs not truncated to make room for the pinned section")
+				}
+			},
+		},
+		{
+			name:   "an oversized single line is cut by characters",
+			budget: 100,
+			sections: []*Section{
+				{Name: "logs", Content: strings.Repeat("é", 500), Priority: 10, MinTokens: 50, Truncate: TruncateHead},
+			},
+			check: func(t *testing.T, byName map[string]*Section) {
+				logs := byName["logs"]
+				if !strings.HasSuffix(logs.Content, "[... line truncated ...]") || !strings.HasPrefix(logs.Content, "é") {
+					t.Errorf("line not cut at a rune boundary:\n%s", logs.Content)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitted := NewAssembler(tt.budget, charCount).Fit(tt.sections)
+
+			total := 0
+			byName := map[string]*Section{}
+			for i, s := range fitted {
+				if s != tt.sections[i] {
+					t.Fatal("Fit changed the section order")
+				}
+				total += s.Tokens
+				byName[s.Name] = s
+			}
+			if total > tt.budget {
+				t.Errorf("fitted sections use %d tokens, budget %d", total, tt.budget)
+			}
+			tt.check(t, byName)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	sections := []*Section{
+		{Title: "Pod Information", Content: "Phase: Running\n"},
+		{Title: "Pod Events", Content: ""},
+		{Title: "Pod Logs", Content: "boom", Untrusted: true, Warning: "possible prompt injection"},
+	}
+
+	want := "Pod Information:\nPhase: Running\n\nPod Logs (WARNING: possible prompt injection):\n" + Fence("boom")
+	if got := Render(sections); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+package prompt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// fenceMarker starts the lines that open and close untrusted content
+const fenceMarker = "UNTRUSTED_DATA"
+
+// fenceSalt is mixed into fence IDs. Set a private value at build time with
+// -ldflags "-X github.com/adiii717/kube-ai-sre-agent/pkg/prompt.fenceSalt=..."
+var fenceSalt = "kube-ai-sre-agent"
+
+// fenceID derives the marker ID from the fenced content, so the same prompt
+// renders identically across runs (recordings replay) while content cannot
+// predict the ID of its own fence and close it early
+func fenceID(content string) string {
+	sum := sha256.Sum256([]byte(fenceSalt + "\x00" + content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Fence wraps content that may be attacker-controlled (logs, events, status
+// messages) in delimiters the model is told to treat as data only
+func Fence(content string) string {
+	content = strings.TrimRight(strings.ReplaceAll(content, fenceMarker, "UNTRUSTED-DATA"), "\n")
+	id := fenceID(content)
+	return fmt.Sprintf("<<<%s id=%s>>>\n%s\n<<<END_%s id=%s>>>",
+		fenceMarker, id, content, fenceMarker, id)
+}
+
+// Body returns the content as it is sent to the model, fenced when untrusted
+func (s *Section) Body() string {
+	if !s.Untrusted || s.Content == "" {
+		return s.Content
+	}
+	return Fence(s.Content)
+}
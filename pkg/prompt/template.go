@@ -0,0 +1,215 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+)
+
+// Data is the value prompt templates are executed with
+type Data struct {
+	EventType string
+	// Kind is set for incidents about objects other than pods; PodName then
+	// holds the object's name
+	Kind          string
+	PodName       string
+	Namespace     string
+	ContainerName string
+	// ContainerKind is init or ephemeral for containers other than the regular ones
+	ContainerKind string
+	Reason        string
+	Message       string
+
+	// Sections holds the collected context in assembly order
+	Sections []*Section
+	// Context is all sections rendered with their titles
+	Context string
+}
+
+// Section returns the named section, or nil if it was not collected
+func (d *Data) Section(name string) *Section {
+	for _, s := range d.Sections {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+const defaultSystemTemplate = `You are a Kubernetes SRE expert. Analyze pod incidents and provide concise, actionable troubleshooting guidance.
+Base your analysis only on the provided context and say so when the context is insufficient.`
+
+const defaultUserTemplate = `Analyze this Kubernetes incident:
+
+Event Type: {{ .EventType }}
+{{ with .Kind }}{{ . }}{{ else }}Pod{{ end }}: {{ .Namespace }}/{{ .PodName }}
+{{- with .ContainerName }}
+Container: {{ . }}
+{{- end }}
+{{- if and .ContainerName (ne .ContainerKind "") (ne .ContainerKind "container") }} ({{ .ContainerKind }} container){{ end }}
+{{- with .Reason }}
+Reason:
+{{ fence . }}
+{{- end }}
+{{- with .Message }}
+Message:
+{{ fence . }}
+{{- end }}
+{{- with guidance }}
+
+Guidance:
+{{ . }}
+{{- end }}
+
+Context:
+{{ .Context }}`
+
+// defaultGuidance is rendered by {{ guidance }} per event type unless overridden in config
+var defaultGuidance = map[string]string{
+	"CrashLoopBackOff": `- Look for the last error before the container exited in the previous container logs.
+- Distinguish application errors (exceptions, missing config, failed dependencies) from exit signals and probe kills.
+- Check the exit code: 1 is usually an application error, 137 SIGKILL, 143 SIGTERM.
+- For init containers (migrations, wait-for scripts), check the database, service or file they depend on before the app can start.`,
+	"ImagePullBackOff": `- Check the image name, tag and registry host in the events for typos or missing tags.
+- Distinguish "not found" from authentication errors (missing or wrong imagePullSecrets) and registry rate limits.
+- Do not suggest code changes; this is a deployment or registry problem.`,
+	"OOMKilled": `- Compare the container memory limit with the workload's actual needs shown in the logs.
+- Distinguish a memory leak (steady growth) from a limit that is simply too low (spike on startup or load).
+- Suggest concrete memory request/limit values and runtime settings (e.g. JVM -Xmx, GOMEMLIMIT) when relevant.`,
+	"FailedScheduling": `- Read the scheduler message: it counts the nodes rejected for each reason (resources, taints, affinity, volume zones).
+- Compare the pod's requests, node selectors, affinities and tolerations with what the nodes offer.
+- Say whether adding capacity, relaxing a constraint or fixing a typo in a selector resolves it.`,
+	"FailedMount": `- Identify the volume that failed to mount and whether it is a Secret, ConfigMap, PVC or CSI volume.
+- Check for missing Secrets/ConfigMaps or keys, unbound PVCs, and volumes still attached to another node.
+- Distinguish a timeout (often an attach problem) from a configuration error in the pod spec.`,
+	"FailedAttachVolume": `- Check whether the volume is still attached to another node (Multi-Attach error) or the node's attach limit is reached.
+- Consider the CSI driver and cloud provider permissions and the volume's availability zone.`,
+	"FailedCreatePodSandBox": `- This is a node-level failure before any container starts: look at the CNI plugin, IP address exhaustion and the container runtime.
+- Check whether the failure is limited to one node and whether the CNI pods on that node are healthy.`,
+	"NetworkNotReady": `- The node's network plugin is not ready; check the CNI daemonset pods on the node and the node conditions.
+- Do not suggest application changes; this is a node or CNI problem.`,
+	"HealthCheckFailure": `- Identify which probe failed (liveness, readiness or startup) and the failure message.
+- Check whether probe timing (initialDelaySeconds, timeoutSeconds, failureThreshold) fits the application's startup time.
+- Distinguish a slow or overloaded application from a wrong probe path or port.`,
+}
+
+// Templates renders the system and user prompts for an incident
+type Templates struct {
+	system   *template.Template
+	user     *template.Template
+	byEvent  map[string]*template.Template
+	guidance map[string]string
+}
+
+// NewTemplates parses prompt templates from cfg, using built-in defaults for
+// anything not configured
+func NewTemplates(cfg *config.PromptsConfig) (*Templates, error) {
+	if cfg == nil {
+		cfg = &config.PromptsConfig{}
+	}
+
+	t := &Templates{
+		byEvent:  map[string]*template.Template{},
+		guidance: map[string]string{},
+	}
+	for eventType, text := range defaultGuidance {
+		t.guidance[eventType] = text
+	}
+	for eventType, text := range cfg.Guidance {
+		t.guidance[eventType] = strings.TrimSpace(text)
+	}
+
+	var err error
+	if t.system, err = parse("system", orDefault(cfg.System, defaultSystemTemplate)); err != nil {
+		return nil, err
+	}
+	if t.user, err = parse("default", orDefault(cfg.Default, defaultUserTemplate)); err != nil {
+		return nil, err
+	}
+	for eventType, text := range cfg.EventTypes {
+		if t.byEvent[eventType], err = parse(eventType, text); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// Render executes the system template and the user template selected by the
+// incident's event type
+func (t *Templates) Render(data *Data) (system, user string, err error) {
+	funcs := template.FuncMap{
+		"guidance": func() string { return t.guidance[data.EventType] },
+		"section": func(name string) string {
+			if s := data.Section(name); s != nil {
+				return s.Body()
+			}
+			return ""
+		},
+	}
+
+	if system, err = execute(t.system, funcs, data); err != nil {
+		return "", "", err
+	}
+
+	tmpl, ok := t.byEvent[data.EventType]
+	if !ok {
+		tmpl = t.user
+	}
+	if user, err = execute(tmpl, funcs, data); err != nil {
+		return "", "", err
+	}
+
+	return system, user, nil
+}
+
+func parse(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(baseFuncs()).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s prompt template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func execute(tmpl *template.Template, funcs template.FuncMap, data *Data) (string, error) {
+	clone, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := clone.Funcs(funcs).Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt template: %w", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// baseFuncs are available to all templates. guidance and section depend on the
+// incident and are rebound on every render.
+//
+//	{{ guidance }}                built-in advice for the event type
+//	{{ section "previousLogs" }}  content of a single context section
+//	{{ fence .Message }}          untrusted text wrapped as data for the model
+//	{{ .Reason | default "n/a" }}
+func baseFuncs() template.FuncMap {
+	return template.FuncMap{
+		"guidance": func() string { return "" },
+		"section":  func(name string) string { return "" },
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"trim":     strings.TrimSpace,
+		"fence":    Fence,
+		"default": func(fallback, value string) string {
+			return orDefault(value, fallback)
+		},
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,119 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	data := &Data{
+		EventType:     "OOMKilled",
+		PodName:       "api-7d9f",
+		Namespace:     "shop",
+		ContainerName: "api",
+		Sections: []*Section{
+			{Name: "logs", Title: "Pod Logs", Content: "allocating buffer", Untrusted: true},
+			{Name: "pod", Title: "Pod Information", Content: "Limits: memory=256Mi"},
+		},
+		Context: "Pod Logs:\nallocating buffer",
+	}
+
+	tests := []struct {
+		name       string
+		cfg        *config.PromptsConfig
+		data       *Data
+		wantSystem []string
+		wantUser   []string
+		notUser    []string
+	}{
+		{
+			name:       "built-in defaults",
+			cfg:        nil,
+			data:       data,
+			wantSystem: []string{"Kubernetes SRE expert"},
+			wantUser:   []string{"Event Type: OOMKilled", "Pod: shop/api-7d9f", "Container: api", "Guidance:", "memory limit", "Context:\nPod Logs:"},
+		},
+		{
+			name: "template per event type",
+			cfg: &config.PromptsConfig{
+				System:     "You support the {{ .Namespace }} team.",
+				EventTypes: map[string]string{"OOMKilled": "{{ .PodName | upper }} ran out of memory.\n{{ section \"pod\" }}\n{{ section \"logs\" }}"},
+			},
+			data:       data,
+			wantSystem: []string{"You support the shop team."},
+			wantUser:   []string{"API-7D9F ran out of memory.", "Limits: memory=256Mi", Fence("allocating buffer")},
+			notUser:    []string{"Event Type:"},
+		},
+		{
+			name: "default template for other event types",
+			cfg: &config.PromptsConfig{
+				Default:    "Incident {{ .EventType }}: {{ .Reason | default \"n/a\" }}",
+				EventTypes: map[string]string{"ImagePullBackOff": "pull"},
+			},
+			data:     data,
+			wantUser: []string{"Incident OOMKilled: n/a"},
+		},
+		{
+			name: "configured guidance replaces the built-in one",
+			cfg: &config.PromptsConfig{
+				Guidance: map[string]string{"OOMKilled": "  Our JVMs use -Xmx at 75% of the limit.\n"},
+			},
+			data:     data,
+			wantUser: []string{"Guidance:\nOur JVMs use -Xmx at 75% of the limit."},
+			notUser:  []string{"GOMEMLIMIT"},
+		},
+		{
+			name:     "unknown section renders empty",
+			cfg:      &config.PromptsConfig{Default: "[{{ section \"missing\" }}]"},
+			data:     data,
+			wantUser: []string{"[]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := NewTemplates(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewTemplates: %v", err)
+			}
+			system, user, err := templates.Render(tt.data)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			for _, want := range tt.wantSystem {
+				if !strings.Contains(system, want) {
+					t.Errorf("system prompt does not contain %q:\n%s", want, system)
+				}
+			}
+			for _, want := range tt.wantUser {
+				if !strings.Contains(user, want) {
+					t.Errorf("user prompt does not contain %q:\n%s", want, user)
+				}
+			}
+			for _, unwanted := range tt.notUser {
+				if strings.Contains(user, unwanted) {
+					t.Errorf("user prompt contains %q:\n%s", unwanted, user)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTemplatesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *config.PromptsConfig
+	}{
+		{"system", &config.PromptsConfig{System: "{{ .EventType"}},
+		{"default", &config.PromptsConfig{Default: "{{ if }}"}},
+		{"event type", &config.PromptsConfig{EventTypes: map[string]string{"OOMKilled": "{{ nosuchfunc }}"}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewTemplates(tt.cfg); err == nil {
+			t.Errorf("%s: NewTemplates accepted an invalid template", tt.name)
+		}
+	}
+}
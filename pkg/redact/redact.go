@@ -0,0 +1,257 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/adiii717/kube-ai-sre-agent/pkg/config"
+)
+
+// Detector finds one kind of sensitive value. When Pattern has a group named
+// "secret" only that group is replaced, keeping surrounding context readable.
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Validate filters false positives (e.g. Luhn check for card numbers)
+	Validate func(match string) bool
+	// Restorable values are put back into the analysis after it returns.
+	// Credentials never are: they would leak into notifications.
+	Restorable bool
+}
+
+// builtinDetectors run in order; URL credentials go before emails because
+// "user:pass@host" would otherwise be taken for an address
+var builtinDetectors = []Detector{
+	{
+		Name:    "url-password",
+		Pattern: regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:(?P<secret>[^\s@/]+)@`),
+	},
+	{
+		Name:    "jwt",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]+`),
+	},
+	{
+		Name:    "bearer-token",
+		Pattern: regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9\-._~+/]{8,}=*)`),
+	},
+	{
+		Name:    "aws-access-key",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA)[0-9A-Z]{16}\b`),
+	},
+	{
+		Name:    "aws-secret-key",
+		Pattern: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`),
+	},
+	{
+		Name:     "credit-card",
+		Pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Validate: cardNumberValid,
+	},
+	{
+		Name:       "email",
+		Pattern:    regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+		Restorable: true,
+	},
+	{
+		Name:       "ipv4",
+		Pattern:    regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+		Restorable: true,
+	},
+	{
+		Name:       "ipv6",
+		Pattern:    regexp.MustCompile(`\b(?:[0-9a-fA-F]{1,4}:){7}[0-9a-fA-F]{1,4}\b|\b(?:[0-9a-fA-F]{1,4}:){1,6}:(?:[0-9a-fA-F]{1,4}:?){1,6}\b`),
+		Restorable: true,
+	},
+}
+
+// Redactor replaces sensitive values with stable placeholders such as
+// [REDACTED_EMAIL_1] and remembers the mapping so output can be re-hydrated
+type Redactor struct {
+	detectors []Detector
+	// placeholders maps detector name + original value to its placeholder
+	placeholders map[string]string
+	// originals maps placeholder back to the original value
+	originals  map[string]string
+	restorable map[string]bool
+	counts     map[string]int
+}
+
+// New creates a redactor with the built-in detectors (minus those disabled in
+// cfg) followed by the user-defined patterns
+func New(cfg *config.RedactionConfig) (*Redactor, error) {
+	disabled := map[string]bool{}
+	for _, name := range cfg.DisabledDetectors {
+		disabled[name] = true
+	}
+
+	r := &Redactor{
+		placeholders: map[string]string{},
+		originals:    map[string]string{},
+		restorable:   map[string]bool{},
+		counts:       map[string]int{},
+	}
+	for _, d := range builtinDetectors {
+		if !disabled[d.Name] {
+			r.detectors = append(r.detectors, d)
+		}
+	}
+
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p.Name, err)
+		}
+		name := p.Name
+		if name == "" {
+			name = "custom"
+		}
+		r.detectors = append(r.detectors, Detector{Name: name, Pattern: re, Restorable: p.Restore})
+	}
+
+	return r, nil
+}
+
+// Redact replaces every sensitive value in text with its placeholder
+func (r *Redactor) Redact(text string) string {
+	for _, d := range r.detectors {
+		text = r.apply(d, text)
+	}
+	return text
+}
+
+// Restore puts restorable original values back in place of their placeholders.
+// Placeholders of credentials are left as they are.
+func (r *Redactor) Restore(text string) string {
+	if len(r.originals) == 0 || !strings.Contains(text, "[REDACTED_") {
+		return text
+	}
+	for placeholder, original := range r.originals {
+		if r.restorable[placeholder] {
+			text = strings.ReplaceAll(text, placeholder, original)
+		}
+	}
+	return text
+}
+
+// Counts returns the number of redacted occurrences per detector
+func (r *Redactor) Counts() map[string]int {
+	counts := make(map[string]int, len(r.counts))
+	for name, n := range r.counts {
+		counts[name] = n
+	}
+	return counts
+}
+
+// Total returns the number of redacted occurrences across all detectors
+func (r *Redactor) Total() int {
+	total := 0
+	for _, n := range r.counts {
+		total += n
+	}
+	return total
+}
+
+// Summary renders the counts as "email: 2, ipv4: 3" sorted by detector name
+func (r *Redactor) Summary() string {
+	names := make([]string, 0, len(r.counts))
+	for name := range r.counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %d", name, r.counts[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (r *Redactor) apply(d Detector, text string) string {
+	secretGroup := d.Pattern.SubexpIndex("secret")
+
+	matches := d.Pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if secretGroup > 0 && m[2*secretGroup] >= 0 {
+			start, end = m[2*secretGroup], m[2*secretGroup+1]
+		}
+
+		value := text[start:end]
+		if strings.HasPrefix(value, "[REDACTED_") || (d.Validate != nil && !d.Validate(value)) {
+			continue
+		}
+
+		b.WriteString(text[last:start])
+		b.WriteString(r.placeholder(d, value))
+		last = end
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
+
+func (r *Redactor) placeholder(d Detector, value string) string {
+	r.counts[d.Name]++
+
+	key := d.Name + "\x00" + value
+	if placeholder, ok := r.placeholders[key]; ok {
+		return placeholder
+	}
+
+	label := strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(d.Name))
+	placeholder := fmt.Sprintf("[REDACTED_%s_%d]", label, countPrefix(r.originals, "[REDACTED_"+label+"_")+1)
+	r.placeholders[key] = placeholder
+	r.originals[placeholder] = value
+	r.restorable[placeholder] = d.Restorable
+	return placeholder
+}
+
+func countPrefix(m map[string]string, prefix string) int {
+	n := 0
+	for k := range m {
+		if strings.HasPrefix(k, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// cardNumberValid accepts issuer prefixes 3-6 (Amex, Visa, Mastercard, Discover, ...)
+// that pass the Luhn checksum, which rules out most timestamps and IDs
+func cardNumberValid(s string) bool {
+	if s == "" || s[0] < '3' || s[0] > '6' {
+		return false
+	}
+	return luhnValid(s)
+}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum
+func luhnValid(s string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
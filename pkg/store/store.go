@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Store persists a JSON-encoded value across analyzer Jobs
+type Store[T any] interface {
+	// Update loads the value (the zero value if none was saved yet), applies fn
+	// and writes the value fn returns when it reports a change.
+	// Implementations may retry fn on concurrent writes.
+	Update(ctx context.Context, fn func(value T) (T, bool)) error
+}
+
+// FileStore keeps the value in a JSON file, e.g. on a PVC shared by analyzer
+// Jobs. Writes are not locked across processes.
+type FileStore[T any] struct {
+	path string
+	// what names the stored data in errors, e.g. "cache"
+	what string
+}
+
+// NewFileStore creates a store backed by the file at path
+func NewFileStore[T any](path, what string) *FileStore[T] {
+	return &FileStore[T]{path: path, what: what}
+}
+
+// Update implements Store
+func (s *FileStore[T]) Update(ctx context.Context, fn func(value T) (T, bool)) error {
+	var value T
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s file: %w", s.what, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return fmt.Errorf("failed to parse %s file: %w", s.what, err)
+		}
+	}
+
+	value, changed := fn(value)
+	if !changed {
+		return nil
+	}
+
+	if data, err = json.Marshal(value); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", s.what, err)
+	}
+	// Write then rename so readers never see a partial file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", s.what, err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// ConfigMapStore keeps the value under one key of a ConfigMap. Concurrent
+// writers are detected through the resourceVersion and retried.
+type ConfigMapStore[T any] struct {
+	clientset *kubernetes.Clientset
+	namespace string
+	name      string
+	key       string
+	what      string
+}
+
+// NewConfigMapStore creates a store backed by key in the named ConfigMap
+func NewConfigMapStore[T any](clientset *kubernetes.Clientset, namespace, name, key, what string) *ConfigMapStore[T] {
+	return &ConfigMapStore[T]{clientset: clientset, namespace: namespace, name: name, key: key, what: what}
+}
+
+// Update implements Store
+func (s *ConfigMapStore[T]) Update(ctx context.Context, fn func(value T) (T, bool)) error {
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		cm, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+		create := apierrors.IsNotFound(err)
+		if err != nil && !create {
+			return err
+		}
+		if create {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      s.name,
+					Namespace: s.namespace,
+					Labels: map[string]string{
+						"app.kubernetes.io/name": "kube-ai-sre-agent",
+					},
+				},
+			}
+		}
+
+		var value T
+		if data := cm.Data[s.key]; data != "" {
+			if err := json.Unmarshal([]byte(data), &value); err != nil {
+				return fmt.Errorf("failed to parse %s configmap: %w", s.what, err)
+			}
+		}
+
+		value, changed := fn(value)
+		if !changed {
+			return nil
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		cm.Data = map[string]string{s.key: string(data)}
+
+		if create {
+			_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		} else {
+			_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		}
+		return err
+	})
+}
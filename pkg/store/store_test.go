@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+	s := NewFileStore[map[string]int](path, "test")
+
+	steps := []struct {
+		name string
+		fn   func(value map[string]int) (map[string]int, bool)
+		want map[string]int
+	}{
+		{
+			name: "missing file starts from the zero value",
+			fn: func(value map[string]int) (map[string]int, bool) {
+				if value != nil {
+					t.Errorf("value = %v, want nil", value)
+				}
+				return map[string]int{"a": 1}, true
+			},
+			want: map[string]int{"a": 1},
+		},
+		{
+			name: "unchanged value is not written",
+			fn: func(value map[string]int) (map[string]int, bool) {
+				value["b"] = 2
+				return value, false
+			},
+			want: map[string]int{"a": 1},
+		},
+		{
+			name: "changes are persisted",
+			fn: func(value map[string]int) (map[string]int, bool) {
+				value["a"]++
+				return value, true
+			},
+			want: map[string]int{"a": 2},
+		},
+	}
+
+	for _, step := range steps {
+		if err := s.Update(ctx, step.fn); err != nil {
+			t.Fatalf("%s: Update: %v", step.name, err)
+		}
+		var got map[string]int
+		s.Update(ctx, func(value map[string]int) (map[string]int, bool) {
+			got = value
+			return value, false
+		})
+		if len(got) != len(step.want) {
+			t.Errorf("%s: value = %v, want %v", step.name, got, step.want)
+		}
+		for k, v := range step.want {
+			if got[k] != v {
+				t.Errorf("%s: value = %v, want %v", step.name, got, step.want)
+			}
+		}
+	}
+
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewFileStore[[]string](path, "knowledge base")
+	err := s.Update(context.Background(), func(value []string) ([]string, bool) {
+		t.Error("fn called on a corrupt file")
+		return value, false
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to parse knowledge base file") {
+		t.Errorf("err = %v, want a parse error naming the data", err)
+	}
+}